@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"sync"
+	_ "time/tzdata"
 
 	_ "go-web/docs"
 	"go-web/internal/platform"
@@ -19,11 +21,20 @@ import (
 // @host		localhost:8000
 // @BasePath	/api
 func main() {
-	cfg := platform.NewConfig()
+	cfg, err := platform.LoadConfig(os.Args[1:])
+	if err != nil {
+		slog.Error("failed to load config: ", "error", err.Error())
+		os.Exit(1)
+	}
 	logger := platform.NewLogger(cfg)
 	slog.SetDefault(logger)
 
-	if cfg.MonitorEnabled {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watcher := platform.NewConfigWatcher(cfg, os.Args[1:])
+	go watcher.Run(ctx)
+
+	if cfg.Monitor.Enabled {
 		go func() {
 			slog.Info("monitor server running...", "addr", cfg.MonitorServerAddr())
 			if err := platform.RunMonitor(cfg.MonitorServerAddr()); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -32,18 +43,27 @@ func main() {
 		}()
 	}
 
+	// serverErr reports a server that failed to start or stopped on its own,
+	// such as a database still unreachable once the connect timeout is over.
+	serverErr := make(chan error, 1)
 	go func() {
 		slog.Info("http server running...", "addr", cfg.HttpServerAddr())
-		if err := httpTransport.RunServer(cfg); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			slog.Error("http server failed running: ", "error", err.Error())
+		if err := httpTransport.RunServer(watcher); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
 		}
 	}()
 
 	stopChan := make(chan os.Signal, 1)
 	signal.Notify(stopChan, os.Interrupt)
 
-	<-stopChan
-	slog.Info("received interrupt signal, stopping servers...")
+	exitCode := 0
+	select {
+	case <-stopChan:
+		slog.Info("received interrupt signal, stopping servers...")
+	case err := <-serverErr:
+		slog.Error("http server failed running: ", "error", err.Error())
+		exitCode = 1
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(2)
@@ -63,4 +83,8 @@ func main() {
 	}()
 
 	wg.Wait()
+	if exitCode != 0 {
+		cancel()
+		os.Exit(exitCode)
+	}
 }
@@ -1,12 +1,25 @@
 package models
 
+import "time"
+
 type AuthTokens struct {
 	AccessToken  string
 	RefreshToken string
 }
 
 type RefreshUser struct {
-	Id    string
-	Email string
-	// Add other fields as necessary like Roles, Permissions, etc.
+	Id        string
+	Email     string
+	Roles     []string
+	CreatedAt time.Time
+	// Generation is the session generation of the user the token was issued
+	// under.
+	Generation string
+}
+
+// EmailChange is a pending change of address waiting for the new address to
+// confirm it.
+type EmailChange struct {
+	UserId string
+	Email  string
 }
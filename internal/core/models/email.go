@@ -0,0 +1,38 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var ErrInvalidEmail = errors.New("invalid email address")
+
+// EmailNormalizer gives every spelling of an address the same form, so that
+// it identifies a single account. Surrounding spaces are trimmed and the
+// domain is lowercased and converted to its ASCII (punycode) form. The local
+// part is case sensitive by the standard, but few providers treat it so;
+// LowercaseLocal lowercases it too.
+//
+// Whatever the setting, the store compares addresses ignoring case.
+type EmailNormalizer struct {
+	LowercaseLocal bool
+}
+
+func (n EmailNormalizer) Normalize(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndexByte(email, '@')
+	if at < 1 || at == len(email)-1 {
+		return "", ErrInvalidEmail
+	}
+	local, domain := email[:at], email[at+1:]
+	domain, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", errors.Join(ErrInvalidEmail, err)
+	}
+	if n.LowercaseLocal {
+		local = strings.ToLower(local)
+	}
+	return local + "@" + strings.ToLower(domain), nil
+}
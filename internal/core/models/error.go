@@ -6,9 +6,13 @@ const (
 	ErrInvalidParam  ErrorType = "INVALID_PARAMETER"
 	ErrInvalidBody   ErrorType = "INVALID_BODY"
 	ErrInvalidAccess ErrorType = "INVALID_ACCESS"
+	ErrForbidden     ErrorType = "FORBIDDEN"
 	ErrConflict      ErrorType = "CONFLICT"
 	ErrNotFound      ErrorType = "NOT_FOUND"
+	ErrUnprocessable ErrorType = "UNPROCESSABLE_ENTITY"
+	ErrPrecondition  ErrorType = "PRECONDITION_FAILED"
 	ErrTooManyReq    ErrorType = "TOO_MANY_REQUESTS"
+	ErrUnsupported   ErrorType = "UNSUPPORTED_MEDIA_TYPE"
 	ErrUnknown       ErrorType = "UNKNOWN"
 )
 
@@ -52,6 +56,10 @@ func InvalidAccess(msg string, err error) *AppError {
 	return newAppError(ErrInvalidAccess, msg, err, false)
 }
 
+func Forbidden(msg string, err error) *AppError {
+	return newAppError(ErrForbidden, msg, err, false)
+}
+
 func Conflict(msg string, err error) *AppError {
 	return newAppError(ErrConflict, msg, err, false)
 }
@@ -60,10 +68,22 @@ func NotFound(msg string, err error) *AppError {
 	return newAppError(ErrNotFound, msg, err, false)
 }
 
+func Unprocessable(msg string, err error) *AppError {
+	return newAppError(ErrUnprocessable, msg, err, false)
+}
+
+func PreconditionFailed(msg string, err error) *AppError {
+	return newAppError(ErrPrecondition, msg, err, false)
+}
+
 func TooManyRequests(msg string, err error) *AppError {
 	return newAppError(ErrTooManyReq, msg, err, false)
 }
 
+func Unsupported(msg string, err error) *AppError {
+	return newAppError(ErrUnsupported, msg, err, false)
+}
+
 func Internal(err error) *AppError {
 	return newAppError(ErrUnknown, MsgUnknown, err, true)
 }
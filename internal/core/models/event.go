@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Event is a domain event: something that happened to an aggregate, e.g. a
+// user, that the rest of the platform may react to.
+type Event struct {
+	Id          string
+	Type        string
+	AggregateId string
+	// Payload is the JSON encoded data of the event.
+	Payload    []byte
+	OccurredAt time.Time
+	// Seq orders the events in the outbox. Events of an aggregate are
+	// published in that order.
+	Seq int64
+	// Attempts counts the failed deliveries of the event.
+	Attempts int
+}
+
+// Types of the events of a user, whose id is the aggregate id.
+const (
+	EventUserRegistered  = "user.registered"
+	EventUserLoggedIn    = "user.logged_in"
+	EventPasswordChanged = "user.password_changed"
+	EventEmailChanged    = "user.email_changed"
+	EventUserDeleted     = "user.deleted"
+)
+
+// UserEventPayload is the payload of the events of a user. Email is the
+// address of the user when they registered or changed it.
+type UserEventPayload struct {
+	Email string `json:"email,omitempty"`
+}
@@ -0,0 +1,41 @@
+package models
+
+// FilterOp is the comparison a Filter makes between a field and its value.
+type FilterOp string
+
+const (
+	OpEq     FilterOp = "eq"
+	OpPrefix FilterOp = "prefix"
+	OpGte    FilterOp = "gte"
+	OpLt     FilterOp = "lt"
+)
+
+type Filter struct {
+	Field string
+	Op    FilterOp
+	Value any
+}
+
+// ListQuery selects a page of a resource. Fields are named after the resource,
+// each store maps them to its own representation.
+type ListQuery struct {
+	Filters []Filter
+	Sort    string
+	Desc    bool
+	// After continues the listing past the last item of the previous page.
+	After *Cursor
+	Limit int
+}
+
+// Cursor is the position of an item in a listing: the value of the sort field
+// and the id, which breaks ties.
+type Cursor struct {
+	Value string
+	Id    string
+}
+
+// Page is a page of a listing. Next is nil on the last page.
+type Page[T any] struct {
+	Items []T
+	Next  *Cursor
+}
@@ -1,7 +1,96 @@
 package models
 
+import "time"
+
 type User struct {
-	Id           string
-	Email        string
-	PasswordHash string
+	Id               string
+	Email            string
+	PasswordHash     string
+	DisplayName      string
+	AvatarURL        string
+	Locale           string
+	Timezone         string
+	EmailVerified    bool
+	TwoFactorEnabled bool
+	Roles            []string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	LastLoginAt      *time.Time
+	// DisabledAt is set while an administrator keeps the account from logging
+	// in.
+	DisabledAt *time.Time
+	// DeletedAt is set while a deleted account waits to be purged.
+	DeletedAt *time.Time
+}
+
+const RoleAdmin = "admin"
+
+// Status of an account, derived from DeletedAt and DisabledAt.
+const (
+	UserActive   = "active"
+	UserDisabled = "disabled"
+	UserDeleted  = "deleted"
+)
+
+func (u *User) Status() string {
+	switch {
+	case u.DeletedAt != nil:
+		return UserDeleted
+	case u.DisabledAt != nil:
+		return UserDisabled
+	default:
+		return UserActive
+	}
+}
+
+// Fields of a user that lists can filter or sort on.
+const (
+	UserFieldEmail     = "email"
+	UserFieldStatus    = "status"
+	UserFieldRole      = "role"
+	UserFieldCreatedAt = "created_at"
+)
+
+// ProfileUpdate holds the user editable profile fields. Nil fields are left
+// unchanged.
+type ProfileUpdate struct {
+	DisplayName *string
+	AvatarURL   *string
+	Locale      *string
+	Timezone    *string
+}
+
+// AuditEntry records a security relevant action on an account.
+type AuditEntry struct {
+	Id        string
+	UserId    string
+	Action    string
+	Detail    string
+	CreatedAt time.Time
+}
+
+const (
+	AuditLogin                = "login"
+	AuditPasswordChanged      = "password_changed"
+	AuditEmailChangeRequested = "email_change_requested"
+	AuditEmailChanged         = "email_changed"
+	AuditAccountDeleted       = "account_deleted"
+	AuditAccountDisabled      = "account_disabled"
+	AuditAccountEnabled       = "account_enabled"
+	AuditSessionsRevoked      = "sessions_revoked"
+	AuditTwoFactorReset       = "two_factor_reset"
+	AuditRoleGranted          = "role_granted"
+)
+
+// Session describes an active refresh token without exposing it.
+type Session struct {
+	Id        string
+	CreatedAt time.Time
+}
+
+// UserExport is everything stored about a user.
+type UserExport struct {
+	User     *User
+	Sessions []Session
+	Audit    []AuditEntry
 }
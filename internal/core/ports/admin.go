@@ -0,0 +1,23 @@
+package ports
+
+import (
+	"context"
+
+	"go-web/internal/core/models"
+)
+
+// AdminService manages the accounts of other users. The adminId of the
+// mutating methods is the administrator acting, recorded in the audit log of
+// the account.
+//
+// GrantAdmin bootstraps administrators, which no endpoint can create; its
+// adminId is whatever granted the role, e.g. "config".
+type AdminService interface {
+	ListUsers(ctx context.Context, query models.ListQuery) (*models.Page[models.User], error)
+	GetUser(ctx context.Context, id string) (*models.User, error)
+	DisableUser(ctx context.Context, adminId, id string) (*models.User, error)
+	EnableUser(ctx context.Context, adminId, id string) (*models.User, error)
+	LogoutUser(ctx context.Context, adminId, id string) error
+	ResetTwoFactor(ctx context.Context, adminId, id string) (*models.User, error)
+	GrantAdmin(ctx context.Context, adminId, email string) (*models.User, error)
+}
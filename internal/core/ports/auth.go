@@ -11,5 +11,13 @@ type AuthService interface {
 	Login(ctx context.Context, email, password string) (*models.AuthTokens, error)
 	Refresh(ctx context.Context, refreshToken string) (*models.AuthTokens, error)
 	Logout(ctx context.Context, refreshToken string) error
+	ChangePassword(ctx context.Context, userId, currentPassword, newPassword string) (*models.AuthTokens, error)
+	RequestEmailChange(ctx context.Context, userId, password, newEmail string) error
+	ConfirmEmailChange(ctx context.Context, token string) error
+	DeleteAccount(ctx context.Context, userId, password string) error
 	Validate(token string) (map[string]interface{}, error)
+	// CheckAccess verifies that the account behind an access token is still
+	// active and holds role, and that its sessions were not revoked since the
+	// token was issued under generation.
+	CheckAccess(ctx context.Context, userId, generation, role string) error
 }
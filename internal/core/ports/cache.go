@@ -3,6 +3,9 @@ package ports
 type Cache interface {
 	Set(key string, value interface{}) error
 	SetWithTTL(key string, value interface{}, ttl int) error
+	// Add stores value only if key is not already present and reports whether
+	// it did, so concurrent callers can use it as a lock.
+	Add(key string, value interface{}, ttl int) (bool, error)
 	Get(key string, value interface{}) error
 	Delete(key string) error
 }
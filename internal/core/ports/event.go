@@ -0,0 +1,22 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"go-web/internal/core/models"
+)
+
+// Publisher delivers domain events to the rest of the platform. An event may
+// be delivered more than once, consumers tell duplicates apart by its id.
+type Publisher interface {
+	Publish(ctx context.Context, event *models.Event) error
+}
+
+type EventService interface {
+	// RelayEvents publishes the events of the outbox that are due, in order
+	// per aggregate, and returns how many were published.
+	RelayEvents(ctx context.Context) (int, error)
+	// PurgePublished removes the events published more than retention ago.
+	PurgePublished(ctx context.Context, retention time.Duration) (int64, error)
+}
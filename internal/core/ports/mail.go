@@ -0,0 +1,11 @@
+package ports
+
+import (
+	"context"
+
+	"go-web/internal/core/models"
+)
+
+type Mailer interface {
+	Send(ctx context.Context, mail *models.Mail) error
+}
@@ -4,4 +4,6 @@ import "context"
 
 type RateLimiter interface {
 	Allow(ctx context.Context, key string) (bool, error)
+	// SetLimit changes the allowed rate (events per second) and burst for all keys.
+	SetLimit(limit float64, burst int)
 }
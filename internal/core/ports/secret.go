@@ -0,0 +1,12 @@
+package ports
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrSecretNotFound = errors.New("secret not found")
+
+type SecretProvider interface {
+	Get(ctx context.Context, name string) (string, error)
+}
@@ -2,15 +2,125 @@ package ports
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"go-web/internal/core/models"
 )
 
+// Kinds of ConstraintError, matched with errors.Is.
+var (
+	ErrUniqueViolation     = errors.New("unique violation")
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	ErrCheckViolation      = errors.New("check violation")
+)
+
+// ConstraintError is returned by a store when a write violates a constraint
+// of the data, e.g. a second user with the same email.
+type ConstraintError struct {
+	// Kind is one of the violation errors above.
+	Kind error
+	// Constraint is the name of the violated constraint, if known.
+	Constraint string
+	Err        error
+}
+
+func (e *ConstraintError) Error() string {
+	if e.Constraint == "" {
+		return e.Kind.Error()
+	}
+	return e.Kind.Error() + " on " + e.Constraint
+}
+
+func (e *ConstraintError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+type primaryKey struct{}
+
+// WithPrimary makes the reads done with ctx go to the primary database instead
+// of a replica, which may lag behind. Use it where a read must see the writes
+// that came before it, e.g. to check a password or before writing what was
+// read.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// UsePrimary tells whether ctx comes from WithPrimary.
+func UsePrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+// Store reads from replicas when it has some, except in transactions and with
+// a context from WithPrimary.
 type Store interface {
 	UserStore
+	AuditStore
+	OutboxStore
+	// WithTx runs fn in a transaction, committed when fn returns nil and
+	// rolled back when it fails or panics. fn must only use the tx store it is
+	// given and may be run again when the transaction hits a serialization
+	// failure. WithTx inside a transaction joins it.
+	WithTx(ctx context.Context, fn func(tx Store) error) error
 }
 
 type UserStore interface {
+	// Create fails with ErrUniqueViolation when the email is taken, ignoring
+	// case.
 	Create(ctx context.Context, user *models.User) (*models.User, error)
+	// FindByEmail compares addresses ignoring case.
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByID(ctx context.Context, id string) (*models.User, error)
+	// Update saves the profile fields of user if it was not modified since
+	// user.UpdatedAt. It returns nil when the user is missing or was modified.
+	Update(ctx context.Context, user *models.User) (*models.User, error)
+	UpdateLastLogin(ctx context.Context, id string, at time.Time) error
+	UpdatePassword(ctx context.Context, id, passwordHash string) error
+	// UpdateEmail also marks the address as verified, since changing it
+	// requires a confirmation from the new address.
+	UpdateEmail(ctx context.Context, id, email string) error
+	SoftDelete(ctx context.Context, id string, at time.Time) error
+	// PurgeDeleted removes the accounts soft deleted before the given time and
+	// returns how many were removed.
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+	// ListUsers returns up to query.Limit users matching query, in the order
+	// it asks for.
+	ListUsers(ctx context.Context, query models.ListQuery) ([]models.User, error)
+	// SetDisabled disables the account at the given time, or enables it again
+	// when at is nil.
+	SetDisabled(ctx context.Context, id string, at *time.Time) error
+	ResetTwoFactor(ctx context.Context, id string) error
+	UpdateRoles(ctx context.Context, id string, roles []string) error
+}
+
+type AuditStore interface {
+	AddAudit(ctx context.Context, entry *models.AuditEntry) error
+	ListAudit(ctx context.Context, userId string) ([]models.AuditEntry, error)
+}
+
+// OutboxStore keeps the domain events until they are published.
+type OutboxStore interface {
+	// AddEvent must run in the transaction of the change the event records,
+	// so that both are saved or neither is.
+	AddEvent(ctx context.Context, event *models.Event) error
+	// ClaimEvents leases up to limit unpublished events until now+lease, so
+	// that concurrent relays do not publish them too, and returns them in
+	// order. The events of an aggregate are only claimed while none of them
+	// is leased or waiting to be retried, so that they are published in order.
+	ClaimEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Event, error)
+	MarkEventPublished(ctx context.Context, id string, at time.Time) error
+	// MarkEventFailed counts a failed delivery and retries the event at
+	// retryAt.
+	MarkEventFailed(ctx context.Context, id string, retryAt time.Time, reason string) error
+	// DeferEvent ends the lease of a claimed event that was not processed and
+	// makes it available at availableAt, without counting an attempt.
+	DeferEvent(ctx context.Context, id string, availableAt time.Time) error
+	// PurgePublishedEvents removes the events published before the given time
+	// and returns how many were removed.
+	PurgePublishedEvents(ctx context.Context, before time.Time) (int64, error)
 }
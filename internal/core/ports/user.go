@@ -0,0 +1,15 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"go-web/internal/core/models"
+)
+
+type UserService interface {
+	Get(ctx context.Context, id string) (*models.User, error)
+	UpdateProfile(ctx context.Context, user *models.User, update models.ProfileUpdate) (*models.User, error)
+	Export(ctx context.Context, id string) (*models.UserExport, error)
+	PurgeDeleted(ctx context.Context, grace time.Duration) (int64, error)
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
+)
+
+type adminService struct {
+	store ports.Store
+	cache ports.Cache
+}
+
+func NewAdminService(store ports.Store, cache ports.Cache) ports.AdminService {
+	return &adminService{store, cache}
+}
+
+func (a *adminService) ListUsers(ctx context.Context, query models.ListQuery) (*models.Page[models.User], error) {
+	limit := query.Limit
+	query.Limit++
+	users, err := a.store.ListUsers(ctx, query)
+	if err != nil {
+		return nil, models.Internal(err)
+	}
+	return paginate(users, limit, func(u *models.User) models.Cursor {
+		value := u.CreatedAt.Format(time.RFC3339Nano)
+		if query.Sort == models.UserFieldEmail {
+			value = u.Email
+		}
+		return models.Cursor{Value: value, Id: u.Id}
+	}), nil
+}
+
+// GetUser returns the user whatever its status, deleted accounts included.
+func (a *adminService) GetUser(ctx context.Context, id string) (*models.User, error) {
+	user, err := a.store.FindByID(ctx, id)
+	if err != nil {
+		return nil, models.Internal(err)
+	}
+	if user == nil {
+		return nil, models.NotFound("User not found", nil)
+	}
+	return user, nil
+}
+
+// DisableUser keeps the user from logging in and revokes their sessions.
+func (a *adminService) DisableUser(ctx context.Context, adminId, id string) (*models.User, error) {
+	if adminId == id {
+		return nil, models.Forbidden("Administrators cannot disable their own account", nil)
+	}
+	user, err := a.activeUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.DisabledAt != nil {
+		return user, nil
+	}
+	now := time.Now()
+	err = a.store.WithTx(ctx, func(tx ports.Store) error {
+		if err := tx.SetDisabled(ctx, id, &now); err != nil {
+			return err
+		}
+		return tx.AddAudit(ctx, newAuditEntry(id, models.AuditAccountDisabled, adminId))
+	})
+	if err != nil {
+		return nil, models.Internal(err)
+	}
+	if err := revokeSessions(a.cache, id); err != nil {
+		return nil, models.Internal(err)
+	}
+	return a.GetUser(ports.WithPrimary(ctx), id)
+}
+
+func (a *adminService) EnableUser(ctx context.Context, adminId, id string) (*models.User, error) {
+	user, err := a.activeUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.DisabledAt == nil {
+		return user, nil
+	}
+	err = a.store.WithTx(ctx, func(tx ports.Store) error {
+		if err := tx.SetDisabled(ctx, id, nil); err != nil {
+			return err
+		}
+		return tx.AddAudit(ctx, newAuditEntry(id, models.AuditAccountEnabled, adminId))
+	})
+	if err != nil {
+		return nil, models.Internal(err)
+	}
+	return a.GetUser(ports.WithPrimary(ctx), id)
+}
+
+// LogoutUser revokes every refresh token of the user. Access tokens that were
+// already issued stay valid until they expire, except on the admin routes.
+func (a *adminService) LogoutUser(ctx context.Context, adminId, id string) error {
+	if _, err := a.activeUser(ctx, id); err != nil {
+		return err
+	}
+	if err := revokeSessions(a.cache, id); err != nil {
+		return models.Internal(err)
+	}
+	recordAudit(ctx, a.store, id, models.AuditSessionsRevoked, adminId)
+	return nil
+}
+
+func (a *adminService) ResetTwoFactor(ctx context.Context, adminId, id string) (*models.User, error) {
+	user, err := a.activeUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled {
+		return user, nil
+	}
+	err = a.store.WithTx(ctx, func(tx ports.Store) error {
+		if err := tx.ResetTwoFactor(ctx, id); err != nil {
+			return err
+		}
+		return tx.AddAudit(ctx, newAuditEntry(id, models.AuditTwoFactorReset, adminId))
+	})
+	if err != nil {
+		return nil, models.Internal(err)
+	}
+	return a.GetUser(ports.WithPrimary(ctx), id)
+}
+
+// GrantAdmin gives the administrator role to the account registered with
+// email. Granting it again changes nothing.
+func (a *adminService) GrantAdmin(ctx context.Context, adminId, email string) (*models.User, error) {
+	user, err := a.store.FindByEmail(ports.WithPrimary(ctx), email)
+	if err != nil {
+		return nil, models.Internal(err)
+	}
+	if user == nil || user.DeletedAt != nil {
+		return nil, models.NotFound("User not found", nil)
+	}
+	if slices.Contains(user.Roles, models.RoleAdmin) {
+		return user, nil
+	}
+	roles := append(slices.Clone(user.Roles), models.RoleAdmin)
+	err = a.store.WithTx(ctx, func(tx ports.Store) error {
+		if err := tx.UpdateRoles(ctx, user.Id, roles); err != nil {
+			return err
+		}
+		return tx.AddAudit(ctx, newAuditEntry(user.Id, models.AuditRoleGranted, adminId))
+	})
+	if err != nil {
+		return nil, models.Internal(err)
+	}
+	return a.GetUser(ports.WithPrimary(ctx), user.Id)
+}
+
+// activeUser returns the user unless it is missing or deleted, since deleted
+// accounts only wait to be purged. It reads from the primary since callers
+// change the user depending on what they read.
+func (a *adminService) activeUser(ctx context.Context, id string) (*models.User, error) {
+	user, err := a.GetUser(ports.WithPrimary(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, models.NotFound("User not found", nil)
+	}
+	return user, nil
+}
@@ -0,0 +1,153 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
+	"go-web/internal/core/service"
+	"go-web/tests/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAdminService_ListUsers(t *testing.T) {
+	ctx := context.Background()
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	users := []models.User{
+		{Id: "1", Email: "a@test.com", CreatedAt: created},
+		{Id: "2", Email: "b@test.com", CreatedAt: created.Add(time.Second)},
+		{Id: "3", Email: "c@test.com", CreatedAt: created.Add(2 * time.Second)},
+	}
+
+	t.Run("should return a cursor when more users follow", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		store.On("ListUsers", ctx, mock.MatchedBy(func(q models.ListQuery) bool {
+			return q.Limit == 3
+		})).Return(users, nil)
+		page, err := service.NewAdminService(store, new(mocks.MockCache)).ListUsers(ctx, models.ListQuery{Sort: models.UserFieldCreatedAt, Limit: 2})
+		assert.NoError(t, err)
+		assert.Len(t, page.Items, 2)
+		assert.Equal(t, &models.Cursor{Value: "2024-05-01T12:00:01Z", Id: "2"}, page.Next)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("should not return a cursor on the last page", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		store.On("ListUsers", ctx, mock.Anything).Return(users, nil)
+		page, err := service.NewAdminService(store, new(mocks.MockCache)).ListUsers(ctx, models.ListQuery{Sort: models.UserFieldEmail, Limit: 3})
+		assert.NoError(t, err)
+		assert.Len(t, page.Items, 3)
+		assert.Nil(t, page.Next)
+	})
+}
+
+func TestAdminService_DisableUser(t *testing.T) {
+	ctx := context.Background()
+	// The user is read from the primary since it is then changed.
+	primary := ports.WithPrimary(ctx)
+
+	t.Run("should disable the user and revoke their sessions", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		cache := new(mocks.MockCache)
+		store.On("FindByID", primary, "1").Return(&models.User{Id: "1"}, nil).Once()
+		store.On("SetDisabled", ctx, "1", mock.MatchedBy(func(at *time.Time) bool { return at != nil })).Return(nil)
+		cache.On("Set", "session_gen:1", mock.Anything).Return(nil)
+		cache.On("Get", "sessions:1", mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(1).(*[]string) = []string{"session-a"}
+		}).Return(nil)
+		cache.On("Delete", "session-a").Return(nil)
+		cache.On("Delete", "sessions:1").Return(nil)
+		store.On("AddAudit", ctx, mock.MatchedBy(func(e *models.AuditEntry) bool {
+			return e.UserId == "1" && e.Action == models.AuditAccountDisabled && e.Detail == "admin"
+		})).Return(nil)
+		now := time.Now()
+		store.On("FindByID", primary, "1").Return(&models.User{Id: "1", DisabledAt: &now}, nil).Once()
+
+		user, err := service.NewAdminService(store, cache).DisableUser(ctx, "admin", "1")
+		assert.NoError(t, err)
+		assert.Equal(t, models.UserDisabled, user.Status())
+		store.AssertExpectations(t)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("should forbid disabling oneself", func(t *testing.T) {
+		_, err := service.NewAdminService(new(mocks.MockStore), new(mocks.MockCache)).DisableUser(ctx, "1", "1")
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, models.ErrForbidden, appErr.Type)
+	})
+
+	t.Run("should not find deleted users", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		deleted := time.Now()
+		store.On("FindByID", primary, "1").Return(&models.User{Id: "1", DeletedAt: &deleted}, nil)
+		_, err := service.NewAdminService(store, new(mocks.MockCache)).DisableUser(ctx, "admin", "1")
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, models.ErrNotFound, appErr.Type)
+		store.AssertNotCalled(t, "SetDisabled", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAdminService_ResetTwoFactor(t *testing.T) {
+	ctx := context.Background()
+	primary := ports.WithPrimary(ctx)
+	store := new(mocks.MockStore)
+	store.On("FindByID", primary, "1").Return(&models.User{Id: "1", TwoFactorEnabled: true}, nil).Once()
+	store.On("ResetTwoFactor", ctx, "1").Return(nil)
+	store.On("AddAudit", ctx, mock.MatchedBy(func(e *models.AuditEntry) bool {
+		return e.Action == models.AuditTwoFactorReset
+	})).Return(nil)
+	store.On("FindByID", primary, "1").Return(&models.User{Id: "1"}, nil).Once()
+
+	user, err := service.NewAdminService(store, new(mocks.MockCache)).ResetTwoFactor(ctx, "admin", "1")
+	assert.NoError(t, err)
+	assert.False(t, user.TwoFactorEnabled)
+	store.AssertExpectations(t)
+}
+
+func TestAdminService_GrantAdmin(t *testing.T) {
+	ctx := context.Background()
+	primary := ports.WithPrimary(ctx)
+
+	t.Run("should add the admin role and audit it", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		store.On("FindByEmail", primary, "a@test.com").Return(&models.User{Id: "1", Roles: []string{"support"}}, nil)
+		store.On("UpdateRoles", ctx, "1", []string{"support", models.RoleAdmin}).Return(nil)
+		store.On("AddAudit", ctx, mock.MatchedBy(func(e *models.AuditEntry) bool {
+			return e.UserId == "1" && e.Action == models.AuditRoleGranted && e.Detail == "config"
+		})).Return(nil)
+		store.On("FindByID", primary, "1").Return(&models.User{Id: "1", Roles: []string{"support", models.RoleAdmin}}, nil)
+
+		user, err := service.NewAdminService(store, new(mocks.MockCache)).GrantAdmin(ctx, "config", "a@test.com")
+		assert.NoError(t, err)
+		assert.Contains(t, user.Roles, models.RoleAdmin)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("should leave administrators unchanged", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		store.On("FindByEmail", primary, "a@test.com").Return(&models.User{Id: "1", Roles: []string{models.RoleAdmin}}, nil)
+
+		_, err := service.NewAdminService(store, new(mocks.MockCache)).GrantAdmin(ctx, "config", "a@test.com")
+		assert.NoError(t, err)
+		store.AssertNotCalled(t, "UpdateRoles", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should not find unregistered or deleted accounts", func(t *testing.T) {
+		now := time.Now()
+		for _, user := range []*models.User{nil, {Id: "1", DeletedAt: &now}} {
+			store := new(mocks.MockStore)
+			store.On("FindByEmail", primary, "a@test.com").Return(user, nil)
+
+			_, err := service.NewAdminService(store, new(mocks.MockCache)).GrantAdmin(ctx, "config", "a@test.com")
+			var appErr *models.AppError
+			assert.ErrorAs(t, err, &appErr)
+			assert.Equal(t, models.ErrNotFound, appErr.Type)
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
+
+	"github.com/google/uuid"
+)
+
+func newAuditEntry(userId, action, detail string) *models.AuditEntry {
+	return &models.AuditEntry{
+		Id:        uuid.NewString(),
+		UserId:    userId,
+		Action:    action,
+		Detail:    detail,
+		CreatedAt: time.Now(),
+	}
+}
+
+// recordAudit appends an entry to the audit log of the user. The action has
+// already happened, so a failure to record it is only logged. Changes that
+// must not happen unaudited add their entry in the same transaction instead.
+func recordAudit(ctx context.Context, store ports.AuditStore, userId, action, detail string) {
+	if err := store.AddAudit(ctx, newAuditEntry(userId, action, detail)); err != nil {
+		slog.Warn("failed to record audit entry", "user", userId, "action", action, "error", err.Error())
+	}
+}
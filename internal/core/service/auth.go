@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
 	"go-web/internal/core/models"
@@ -16,13 +19,26 @@ type authService struct {
 	cache  ports.Cache
 	hasher ports.Hasher
 	token  ports.TokenGenerator
+	mailer ports.Mailer
+	emails models.EmailNormalizer
 }
 
-func NewAuthService(store ports.Store, cache ports.Cache, hasher ports.Hasher, token ports.TokenGenerator) ports.AuthService {
-	return &authService{store, cache, hasher, token}
+func NewAuthService(store ports.Store, cache ports.Cache, hasher ports.Hasher, token ports.TokenGenerator, mailer ports.Mailer, emails models.EmailNormalizer) ports.AuthService {
+	return &authService{store, cache, hasher, token, mailer, emails}
 }
 
+// Register creates an account. Looking the email up first spares hashing the
+// password of a taken address; the unique constraint of the store settles
+// concurrent registrations. The password policy is the one of ChangePassword,
+// so that users can always change to the password they signed up with.
 func (a *authService) Register(ctx context.Context, email, password string) (*models.User, error) {
+	email, err := a.emails.Normalize(email)
+	if err != nil {
+		return nil, models.InvalidBody("Invalid email address", err)
+	}
+	if err := checkPasswordPolicy(password, email); err != nil {
+		return nil, err
+	}
 	userDb, err := a.store.FindByEmail(ctx, email)
 	if err != nil {
 		return nil, models.Internal(err)
@@ -39,14 +55,25 @@ func (a *authService) Register(ctx context.Context, email, password string) (*mo
 		Email:        email,
 		PasswordHash: hashedPassword,
 	}
-	if _, err := a.store.Create(ctx, user); err != nil {
-		return nil, models.Internal(err)
+	err = a.store.WithTx(ctx, func(tx ports.Store) error {
+		if _, err := tx.Create(ctx, user); err != nil {
+			return err
+		}
+		return tx.AddEvent(ctx, newUserEvent(models.EventUserRegistered, user.Id, models.UserEventPayload{Email: email}))
+	})
+	if err != nil {
+		return nil, storeError(err, "Email already in use")
 	}
 	return user, nil
 }
 
 func (a *authService) Login(ctx context.Context, email, password string) (*models.AuthTokens, error) {
-	user, err := a.store.FindByEmail(ctx, email)
+	email, err := a.emails.Normalize(email)
+	if err != nil {
+		return nil, models.InvalidAccess("Email or password is incorrect", err)
+	}
+	// A replica could still accept a password that was just changed.
+	user, err := a.store.FindByEmail(ports.WithPrimary(ctx), email)
 	if user == nil {
 		return nil, models.InvalidAccess("Email or password is incorrect", err)
 	}
@@ -56,32 +83,28 @@ func (a *authService) Login(ctx context.Context, email, password string) (*model
 	if err := a.hasher.Compare(user.PasswordHash, password); err != nil {
 		return nil, models.InvalidAccess("Email or password is incorrect", err)
 	}
-	claims := map[string]interface{}{
-		"sub":   user.Id,
-		"email": user.Email,
-		"iat":   time.Now().Unix(),
-		"exp":   time.Now().Add(time.Minute * 15).Unix(),
-		"jti":   shared.RandString(8),
-	}
-	accessToken, err := a.token.Generate(claims)
-	if err != nil {
-		return nil, models.Internal(err)
+	if user.DeletedAt != nil {
+		return nil, models.InvalidAccess("Email or password is incorrect", nil)
 	}
-	refreshToken := shared.RandString(16)
-	refreshUser := models.RefreshUser{
-		Id:    user.Id,
-		Email: user.Email,
+	if user.DisabledAt != nil {
+		return nil, models.Forbidden("Account is disabled", nil)
 	}
-	err = a.cache.SetWithTTL(refreshToken, refreshUser, 60*60*24*7) // 7 days
+	err = a.store.WithTx(ctx, func(tx ports.Store) error {
+		if err := tx.UpdateLastLogin(ctx, user.Id, time.Now()); err != nil {
+			return err
+		}
+		return tx.AddEvent(ctx, newUserEvent(models.EventUserLoggedIn, user.Id, models.UserEventPayload{}))
+	})
 	if err != nil {
-		return nil, models.Internal(err)
+		slog.Warn("failed to record last login", "user", user.Id, "error", err.Error())
 	}
-	return &models.AuthTokens{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}, nil
+	recordAudit(ctx, a.store, user.Id, models.AuditLogin, "")
+	return a.newSession(models.RefreshUser{Id: user.Id, Email: user.Email, Roles: user.Roles})
 }
 
+// Refresh replaces a refresh token with a new session. The token is rejected
+// once the sessions of the user were revoked, and the user is read again so
+// that disabled and deleted accounts cannot refresh.
 func (a *authService) Refresh(ctx context.Context, refreshToken string) (*models.AuthTokens, error) {
 	var refreshUser models.RefreshUser
 	err := a.cache.Get(refreshToken, &refreshUser)
@@ -91,36 +114,243 @@ func (a *authService) Refresh(ctx context.Context, refreshToken string) (*models
 	if refreshUser.Id == "" {
 		return nil, models.InvalidAccess("Invalid refresh token", nil)
 	}
-	newClaims := map[string]any{
-		"sub":   refreshUser.Id,
-		"email": refreshUser.Email,
-		"iat":   time.Now().Unix(),
-		"exp":   time.Now().Add(time.Minute * 15).Unix(),
-		"jti":   shared.RandString(8),
+	if err := a.endSession(refreshUser.Id, refreshToken); err != nil {
+		return nil, models.Internal(err)
 	}
-	newAccessToken, err := a.token.Generate(newClaims)
+	gen, err := sessionGeneration(a.cache, refreshUser.Id)
 	if err != nil {
 		return nil, models.Internal(err)
 	}
-	newRefreshToken := shared.RandString(16)
-	err = a.cache.SetWithTTL(newRefreshToken, refreshUser, 60*60*24*7) // 7 days
+	if gen != refreshUser.Generation {
+		return nil, models.InvalidAccess("Invalid refresh token", nil)
+	}
+	user, err := a.store.FindByID(ports.WithPrimary(ctx), refreshUser.Id)
 	if err != nil {
 		return nil, models.Internal(err)
 	}
-	err = a.cache.Delete(refreshToken)
+	if user == nil || user.DeletedAt != nil {
+		return nil, models.InvalidAccess("Invalid refresh token", nil)
+	}
+	if user.DisabledAt != nil {
+		return nil, models.Forbidden("Account is disabled", nil)
+	}
+	return a.issueSession(models.RefreshUser{
+		Id:         user.Id,
+		Email:      user.Email,
+		Roles:      user.Roles,
+		CreatedAt:  refreshUser.CreatedAt,
+		Generation: refreshUser.Generation,
+	})
+}
+
+func (a *authService) Logout(ctx context.Context, refreshToken string) error {
+	var refreshUser models.RefreshUser
+	if err := a.cache.Get(refreshToken, &refreshUser); err != nil || refreshUser.Id == "" {
+		return a.cache.Delete(refreshToken)
+	}
+	return a.endSession(refreshUser.Id, refreshToken)
+}
+
+// ChangePassword replaces the password of a user who proved they know the
+// current one. Every session of the user is revoked and a fresh one is
+// returned for the caller.
+func (a *authService) ChangePassword(ctx context.Context, userId, currentPassword, newPassword string) (*models.AuthTokens, error) {
+	user, err := a.reauthenticate(ctx, userId, currentPassword)
+	if err != nil {
+		return nil, err
+	}
+	if newPassword == currentPassword {
+		return nil, models.InvalidBody("New password must differ from the current one", nil)
+	}
+	if err := checkPasswordPolicy(newPassword, user.Email); err != nil {
+		return nil, err
+	}
+	hashedPassword, err := a.hasher.Hash(newPassword)
 	if err != nil {
 		return nil, models.Internal(err)
 	}
-	return &models.AuthTokens{
-		AccessToken:  newAccessToken,
-		RefreshToken: newRefreshToken,
-	}, nil
+	err = a.store.WithTx(ctx, func(tx ports.Store) error {
+		if err := tx.UpdatePassword(ctx, user.Id, hashedPassword); err != nil {
+			return err
+		}
+		if err := tx.AddAudit(ctx, newAuditEntry(user.Id, models.AuditPasswordChanged, "")); err != nil {
+			return err
+		}
+		return tx.AddEvent(ctx, newUserEvent(models.EventPasswordChanged, user.Id, models.UserEventPayload{}))
+	})
+	if err != nil {
+		return nil, models.Internal(err)
+	}
+	if err := revokeSessions(a.cache, user.Id); err != nil {
+		return nil, models.Internal(err)
+	}
+	return a.newSession(models.RefreshUser{Id: user.Id, Email: user.Email, Roles: user.Roles})
 }
 
-func (a *authService) Logout(ctx context.Context, refreshToken string) error {
-	return a.cache.Delete(refreshToken)
+// RequestEmailChange mails a confirmation token to the new address and a
+// notice to the current one. The address only changes once the token is
+// confirmed through ConfirmEmailChange.
+func (a *authService) RequestEmailChange(ctx context.Context, userId, password, newEmail string) error {
+	user, err := a.reauthenticate(ctx, userId, password)
+	if err != nil {
+		return err
+	}
+	newEmail, err = a.emails.Normalize(newEmail)
+	if err != nil {
+		return models.InvalidBody("Invalid email address", err)
+	}
+	if strings.EqualFold(newEmail, user.Email) {
+		return models.InvalidBody("New email must differ from the current one", nil)
+	}
+	existing, err := a.store.FindByEmail(ctx, newEmail)
+	if err != nil {
+		return models.Internal(err)
+	}
+	if existing != nil {
+		return models.Conflict("Email already in use", nil)
+	}
+	token, err := shared.SecureToken(32)
+	if err != nil {
+		return models.Internal(err)
+	}
+	change := models.EmailChange{UserId: user.Id, Email: newEmail}
+	if err := a.cache.SetWithTTL(emailChangeKey(token), change, 60*60*24); err != nil { // 1 day
+		return models.Internal(err)
+	}
+	confirm := &models.Mail{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body:    "Use this token to confirm your new email address within 24 hours: " + token,
+	}
+	if err := a.mailer.Send(ctx, confirm); err != nil {
+		return models.Internal(err)
+	}
+	notice := &models.Mail{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body:    "A change of your account email to " + newEmail + " was requested. If this was not you, change your password now.",
+	}
+	if err := a.mailer.Send(ctx, notice); err != nil {
+		slog.Warn("failed to send email change notice", "user", user.Id, "error", err.Error())
+	}
+	recordAudit(ctx, a.store, user.Id, models.AuditEmailChangeRequested, newEmail)
+	return nil
+}
+
+// ConfirmEmailChange switches the address of the user who requested token.
+// The sessions of the user carry the old address and are revoked.
+func (a *authService) ConfirmEmailChange(ctx context.Context, token string) error {
+	var change models.EmailChange
+	if err := a.cache.Get(emailChangeKey(token), &change); err != nil || change.UserId == "" {
+		return models.InvalidParam("Invalid or expired confirmation token", err)
+	}
+	err := a.store.WithTx(ctx, func(tx ports.Store) error {
+		existing, err := tx.FindByEmail(ctx, change.Email)
+		if err != nil {
+			return models.Internal(err)
+		}
+		if existing != nil {
+			return models.Conflict("Email already in use", nil)
+		}
+		if err := tx.UpdateEmail(ctx, change.UserId, change.Email); err != nil {
+			return storeError(err, "Email already in use")
+		}
+		if err := tx.AddAudit(ctx, newAuditEntry(change.UserId, models.AuditEmailChanged, change.Email)); err != nil {
+			return models.Internal(err)
+		}
+		if err := tx.AddEvent(ctx, newUserEvent(models.EventEmailChanged, change.UserId, models.UserEventPayload{Email: change.Email})); err != nil {
+			return models.Internal(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := a.cache.Delete(emailChangeKey(token)); err != nil {
+		slog.Warn("failed to delete email change token", "user", change.UserId, "error", err.Error())
+	}
+	if err := revokeSessions(a.cache, change.UserId); err != nil {
+		return models.Internal(err)
+	}
+	return nil
+}
+
+// DeleteAccount soft deletes the account of a user who confirmed their
+// password and revokes all of their sessions. The account is purged for good
+// once the grace period is over.
+func (a *authService) DeleteAccount(ctx context.Context, userId, password string) error {
+	user, err := a.reauthenticate(ctx, userId, password)
+	if err != nil {
+		return err
+	}
+	err = a.store.WithTx(ctx, func(tx ports.Store) error {
+		if err := tx.SoftDelete(ctx, user.Id, time.Now()); err != nil {
+			return err
+		}
+		if err := tx.AddAudit(ctx, newAuditEntry(user.Id, models.AuditAccountDeleted, "")); err != nil {
+			return err
+		}
+		return tx.AddEvent(ctx, newUserEvent(models.EventUserDeleted, user.Id, models.UserEventPayload{}))
+	})
+	if err != nil {
+		return models.Internal(err)
+	}
+	if err := revokeSessions(a.cache, user.Id); err != nil {
+		return models.Internal(err)
+	}
+	notice := &models.Mail{
+		To:      user.Email,
+		Subject: "Your account has been deleted",
+		Body:    "Your account was deleted and will be permanently removed at the end of the grace period. Contact support to restore it before then.",
+	}
+	if err := a.mailer.Send(ctx, notice); err != nil {
+		slog.Warn("failed to send account deletion notice", "user", user.Id, "error", err.Error())
+	}
+	return nil
+}
+
+func (a *authService) reauthenticate(ctx context.Context, userId, password string) (*models.User, error) {
+	user, err := a.store.FindByID(ports.WithPrimary(ctx), userId)
+	if err != nil {
+		return nil, models.Internal(err)
+	}
+	if user == nil || user.DeletedAt != nil {
+		return nil, models.NotFound("User not found", nil)
+	}
+	if err := a.hasher.Compare(user.PasswordHash, password); err != nil {
+		return nil, models.Forbidden("Password is incorrect", err)
+	}
+	return user, nil
+}
+
+func emailChangeKey(token string) string {
+	return "email_change:" + token
 }
 
 func (a *authService) Validate(token string) (map[string]interface{}, error) {
 	return a.token.Validate(token)
 }
+
+// CheckAccess reads the store and the session generation on every call, so it
+// only guards the privileged routes, where the roles of an access token cannot
+// be trusted until it expires.
+func (a *authService) CheckAccess(ctx context.Context, userId, generation, role string) error {
+	user, err := a.store.FindByID(ports.WithPrimary(ctx), userId)
+	if err != nil {
+		return models.Internal(err)
+	}
+	if user == nil || user.DeletedAt != nil {
+		return models.InvalidAccess("Invalid or expired token", nil)
+	}
+	if user.DisabledAt != nil {
+		return models.Forbidden("Account is disabled", nil)
+	}
+	if !slices.Contains(user.Roles, role) {
+		return models.Forbidden("Insufficient role", nil)
+	}
+	var gen string
+	if err := a.cache.Get(generationKey(userId), &gen); err != nil || gen != generation {
+		return models.InvalidAccess("Session has been revoked", err)
+	}
+	return nil
+}
@@ -2,9 +2,13 @@ package service_test
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
 	"go-web/internal/core/service"
 	"go-web/tests/mocks"
 
@@ -20,7 +24,7 @@ func TestAuthService_Register(t *testing.T) {
 		hasher := new(mocks.MockHasher)
 		token := new(mocks.MockToken)
 		email := "user@test.com"
-		password := "password"
+		password := "password1"
 		hashedPassword := "hashedPassword"
 		store.On("FindByEmail", ctx, email).Return((*models.User)(nil), nil)
 		hasher.On("Hash", password).Return(hashedPassword, nil)
@@ -30,7 +34,10 @@ func TestAuthService_Register(t *testing.T) {
 			Email:        email,
 			PasswordHash: hashedPassword,
 		}, nil)
-		authService := service.NewAuthService(store, cache, hasher, token)
+		store.On("AddEvent", ctx, mock.MatchedBy(func(e *models.Event) bool {
+			return e.Type == models.EventUserRegistered && e.AggregateId != "" && string(e.Payload) == `{"email":"user@test.com"}`
+		})).Return(nil)
+		authService := service.NewAuthService(store, cache, hasher, token, new(mocks.MockMailer), models.EmailNormalizer{})
 		user, err := authService.Register(ctx, email, password)
 		assert.NoError(t, err)
 		assert.Equal(t, email, user.Email)
@@ -50,16 +57,110 @@ func TestAuthService_Register(t *testing.T) {
 			Email:        email,
 			PasswordHash: "hashedPassword",
 		}, nil)
-		authService := service.NewAuthService(store, cache, hasher, token)
-		user, err := authService.Register(ctx, email, "password")
+		authService := service.NewAuthService(store, cache, hasher, token, new(mocks.MockMailer), models.EmailNormalizer{})
+		user, err := authService.Register(ctx, email, "password1")
 		assert.Error(t, err)
 		assert.Nil(t, user)
 		store.AssertExpectations(t)
 	})
+
+	t.Run("should normalize the email", func(t *testing.T) {
+		for _, tc := range []struct {
+			emails models.EmailNormalizer
+			want   string
+		}{
+			{models.EmailNormalizer{}, "Bob@xn--bcher-kva.de"},
+			{models.EmailNormalizer{LowercaseLocal: true}, "bob@xn--bcher-kva.de"},
+		} {
+			store := new(mocks.MockStore)
+			hasher := new(mocks.MockHasher)
+			store.On("FindByEmail", ctx, tc.want).Return((*models.User)(nil), nil)
+			hasher.On("Hash", "password1").Return("hashedPassword", nil)
+			store.On("Create", ctx, mock.MatchedBy(func(u *models.User) bool {
+				return u.Email == tc.want
+			})).Return(&models.User{}, nil)
+			store.On("AddEvent", ctx, mock.Anything).Return(nil)
+			authService := service.NewAuthService(store, new(mocks.MockCache), hasher, new(mocks.MockToken), new(mocks.MockMailer), tc.emails)
+			user, err := authService.Register(ctx, " Bob@Bücher.DE ", "password1")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, user.Email)
+			store.AssertExpectations(t)
+		}
+	})
+
+	t.Run("should reject an email it cannot normalize", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		authService := service.NewAuthService(store, new(mocks.MockCache), new(mocks.MockHasher), new(mocks.MockToken), new(mocks.MockMailer), models.EmailNormalizer{})
+		_, err := authService.Register(ctx, "user@-test.com", "password1")
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, models.ErrInvalidBody, appErr.Type)
+		store.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should report a conflict when a concurrent registration wins", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		hasher := new(mocks.MockHasher)
+		email := "user@test.com"
+		store.On("FindByEmail", ctx, email).Return((*models.User)(nil), nil)
+		hasher.On("Hash", "password1").Return("hashedPassword", nil)
+		store.On("Create", ctx, mock.Anything).Return((*models.User)(nil), &ports.ConstraintError{
+			Kind:       ports.ErrUniqueViolation,
+			Constraint: "users_email_key",
+		})
+		authService := service.NewAuthService(store, new(mocks.MockCache), hasher, new(mocks.MockToken), new(mocks.MockMailer), models.EmailNormalizer{})
+		user, err := authService.Register(ctx, email, "password1")
+		assert.Nil(t, user)
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, models.ErrConflict, appErr.Type)
+		assert.ErrorIs(t, err, ports.ErrUniqueViolation)
+	})
+
+	t.Run("should fail when the event cannot be saved with the user", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		hasher := new(mocks.MockHasher)
+		store.On("FindByEmail", ctx, "user@test.com").Return((*models.User)(nil), nil)
+		hasher.On("Hash", "password1").Return("hashedPassword", nil)
+		store.On("Create", ctx, mock.Anything).Return(&models.User{}, nil)
+		store.On("AddEvent", ctx, mock.Anything).Return(errors.New("connection reset"))
+		authService := service.NewAuthService(store, new(mocks.MockCache), hasher, new(mocks.MockToken), new(mocks.MockMailer), models.EmailNormalizer{})
+		user, err := authService.Register(ctx, "user@test.com", "password1")
+		assert.Nil(t, user)
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.True(t, appErr.IsInternal)
+	})
+
+	t.Run("should not treat other store errors as conflicts", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		hasher := new(mocks.MockHasher)
+		store.On("FindByEmail", ctx, "user@test.com").Return((*models.User)(nil), nil)
+		hasher.On("Hash", "password1").Return("hashedPassword", nil)
+		store.On("Create", ctx, mock.Anything).Return((*models.User)(nil), errors.New("connection reset"))
+		authService := service.NewAuthService(store, new(mocks.MockCache), hasher, new(mocks.MockToken), new(mocks.MockMailer), models.EmailNormalizer{})
+		_, err := authService.Register(ctx, "user@test.com", "password1")
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.True(t, appErr.IsInternal)
+	})
+
+	t.Run("should enforce the password policy", func(t *testing.T) {
+		for _, password := range []string{"pass1", "password", "12345678", "xuser@test.com1"} {
+			store := new(mocks.MockStore)
+			authService := service.NewAuthService(store, new(mocks.MockCache), new(mocks.MockHasher), new(mocks.MockToken), new(mocks.MockMailer), models.EmailNormalizer{})
+			_, err := authService.Register(ctx, "user@test.com", password)
+			var appErr *models.AppError
+			assert.ErrorAs(t, err, &appErr, password)
+			assert.Equal(t, models.ErrInvalidBody, appErr.Type, password)
+			store.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		}
+	})
 }
 
 func TestAuthService_Login(t *testing.T) {
 	ctx := context.Background()
+	primary := ports.WithPrimary(ctx)
 	t.Run("should login a user and return a token", func(t *testing.T) {
 		store := new(mocks.MockStore)
 		cache := new(mocks.MockCache)
@@ -69,17 +170,30 @@ func TestAuthService_Login(t *testing.T) {
 		password := "password"
 		hashedPassword := "hashedPassword"
 		expectedAccessToken := "access-token"
-		store.On("FindByEmail", ctx, email).Return(&models.User{
+		store.On("FindByEmail", primary, email).Return(&models.User{
 			Id:           "1",
 			Email:        email,
 			PasswordHash: hashedPassword,
+			Roles:        []string{"user"},
 		}, nil)
-		cache.On("SetWithTTL", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		store.On("UpdateLastLogin", ctx, "1", mock.AnythingOfType("time.Time")).Return(nil)
+		store.On("AddEvent", ctx, mock.MatchedBy(func(e *models.Event) bool {
+			return e.Type == models.EventUserLoggedIn && e.AggregateId == "1"
+		})).Return(nil)
+		store.On("AddAudit", ctx, mock.MatchedBy(func(e *models.AuditEntry) bool {
+			return e.UserId == "1" && e.Action == models.AuditLogin
+		})).Return(nil)
+		cache.On("Get", "session_gen:1", mock.Anything).Run(setGeneration("gen-1")).Return(nil)
+		cache.On("SetWithTTL", mock.Anything, mock.MatchedBy(func(u any) bool {
+			r, ok := u.(models.RefreshUser)
+			return !ok || r.Generation == "gen-1"
+		}), mock.Anything).Return(nil)
+		cache.On("Get", "sessions:1", mock.Anything).Return(assert.AnError)
 		hasher.On("Compare", hashedPassword, password).Return(nil)
 		token.On("Generate", mock.MatchedBy(func(claims map[string]any) bool {
-			return claims["sub"] == "1" && claims["email"] == email
+			return claims["sub"] == "1" && claims["email"] == email && assert.ObjectsAreEqual([]string{"user"}, claims["roles"])
 		})).Return(expectedAccessToken, nil)
-		authService := service.NewAuthService(store, cache, hasher, token)
+		authService := service.NewAuthService(store, cache, hasher, token, new(mocks.MockMailer), models.EmailNormalizer{})
 		tokens, err := authService.Login(ctx, email, password)
 		assert.NoError(t, err)
 		assert.Equal(t, expectedAccessToken, tokens.AccessToken)
@@ -89,6 +203,25 @@ func TestAuthService_Login(t *testing.T) {
 		token.AssertExpectations(t)
 	})
 
+	t.Run("should not login a disabled user", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		hasher := new(mocks.MockHasher)
+		disabled := time.Now()
+		store.On("FindByEmail", primary, "user@test.com").Return(&models.User{
+			Id:           "1",
+			PasswordHash: "hashedPassword",
+			DisabledAt:   &disabled,
+		}, nil)
+		hasher.On("Compare", "hashedPassword", "password").Return(nil)
+		authService := service.NewAuthService(store, new(mocks.MockCache), hasher, new(mocks.MockToken), new(mocks.MockMailer), models.EmailNormalizer{})
+		tokens, err := authService.Login(ctx, "user@test.com", "password")
+		assert.Nil(t, tokens)
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, models.ErrForbidden, appErr.Type)
+		store.AssertNotCalled(t, "UpdateLastLogin", mock.Anything, mock.Anything, mock.Anything)
+	})
+
 	t.Run("should not login with incorrect email", func(t *testing.T) {
 		store := new(mocks.MockStore)
 		cache := new(mocks.MockCache)
@@ -96,8 +229,8 @@ func TestAuthService_Login(t *testing.T) {
 		token := new(mocks.MockToken)
 		email := "wrong@test.com"
 		password := "password"
-		store.On("FindByEmail", ctx, email).Return((*models.User)(nil), nil)
-		authService := service.NewAuthService(store, cache, hasher, token)
+		store.On("FindByEmail", primary, email).Return((*models.User)(nil), nil)
+		authService := service.NewAuthService(store, cache, hasher, token, new(mocks.MockMailer), models.EmailNormalizer{})
 		tokens, err := authService.Login(ctx, email, password)
 		assert.Error(t, err)
 		assert.Nil(t, tokens)
@@ -112,13 +245,13 @@ func TestAuthService_Login(t *testing.T) {
 		email := "user@test.com"
 		password := "wrongPassword"
 		hashedPassword := "hashedPassword"
-		store.On("FindByEmail", ctx, email).Return(&models.User{
+		store.On("FindByEmail", primary, email).Return(&models.User{
 			Id:           "1",
 			Email:        email,
 			PasswordHash: hashedPassword,
 		}, nil)
 		hasher.On("Compare", hashedPassword, password).Return(assert.AnError)
-		authService := service.NewAuthService(store, cache, hasher, token)
+		authService := service.NewAuthService(store, cache, hasher, token, new(mocks.MockMailer), models.EmailNormalizer{})
 		tokens, err := authService.Login(ctx, email, password)
 		assert.Error(t, err)
 		assert.Nil(t, tokens)
@@ -126,3 +259,321 @@ func TestAuthService_Login(t *testing.T) {
 		hasher.AssertExpectations(t)
 	})
 }
+
+// setGeneration makes a mocked cache Get return gen as the session
+// generation.
+func setGeneration(gen string) func(mock.Arguments) {
+	return func(args mock.Arguments) {
+		*args.Get(1).(*string) = gen
+	}
+}
+
+func TestAuthService_Refresh(t *testing.T) {
+	ctx := context.Background()
+	primary := ports.WithPrimary(ctx)
+	created := time.Now().Add(-time.Hour)
+	// refreshCache holds the refresh token "old" of user 1, issued under
+	// generation gen-1, while the current generation is current.
+	refreshCache := func(current string) *mocks.MockCache {
+		cache := new(mocks.MockCache)
+		cache.On("Get", "old", mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(1).(*models.RefreshUser) = models.RefreshUser{Id: "1", Email: "user@test.com", CreatedAt: created, Generation: "gen-1"}
+		}).Return(nil)
+		cache.On("Delete", "old").Return(nil)
+		cache.On("Get", "sessions:1", mock.Anything).Return(assert.AnError)
+		cache.On("SetWithTTL", "sessions:1", mock.Anything, mock.Anything).Return(nil)
+		cache.On("Get", "session_gen:1", mock.Anything).Run(setGeneration(current)).Return(nil)
+		return cache
+	}
+
+	t.Run("should rotate the token and read the roles again", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		cache := refreshCache("gen-1")
+		token := new(mocks.MockToken)
+		store.On("FindByID", primary, "1").Return(&models.User{Id: "1", Email: "user@test.com", Roles: []string{"admin"}}, nil)
+		cache.On("SetWithTTL", mock.MatchedBy(func(key string) bool { return key != "sessions:1" }), mock.MatchedBy(func(u models.RefreshUser) bool {
+			return u.Generation == "gen-1" && u.CreatedAt.Equal(created) && assert.ObjectsAreEqual([]string{"admin"}, u.Roles)
+		}), mock.Anything).Return(nil)
+		token.On("Generate", mock.Anything).Return("access-token", nil)
+		authService := service.NewAuthService(store, cache, new(mocks.MockHasher), token, new(mocks.MockMailer), models.EmailNormalizer{})
+		tokens, err := authService.Refresh(ctx, "old")
+		assert.NoError(t, err)
+		assert.Equal(t, "access-token", tokens.AccessToken)
+		assert.NotEqual(t, "old", tokens.RefreshToken)
+		store.AssertExpectations(t)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("should reject a token issued before the sessions were revoked", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		authService := service.NewAuthService(store, refreshCache("gen-2"), new(mocks.MockHasher), new(mocks.MockToken), new(mocks.MockMailer), models.EmailNormalizer{})
+		tokens, err := authService.Refresh(ctx, "old")
+		assert.Nil(t, tokens)
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, models.ErrInvalidAccess, appErr.Type)
+		store.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should reject disabled and deleted accounts", func(t *testing.T) {
+		now := time.Now()
+		for _, tc := range []struct {
+			user *models.User
+			want models.ErrorType
+		}{
+			{&models.User{Id: "1", DisabledAt: &now}, models.ErrForbidden},
+			{&models.User{Id: "1", DeletedAt: &now}, models.ErrInvalidAccess},
+			{nil, models.ErrInvalidAccess},
+		} {
+			store := new(mocks.MockStore)
+			store.On("FindByID", primary, "1").Return(tc.user, nil)
+			authService := service.NewAuthService(store, refreshCache("gen-1"), new(mocks.MockHasher), new(mocks.MockToken), new(mocks.MockMailer), models.EmailNormalizer{})
+			tokens, err := authService.Refresh(ctx, "old")
+			assert.Nil(t, tokens)
+			var appErr *models.AppError
+			assert.ErrorAs(t, err, &appErr)
+			assert.Equal(t, tc.want, appErr.Type)
+		}
+	})
+}
+
+func TestAuthService_CheckAccess(t *testing.T) {
+	ctx := context.Background()
+	primary := ports.WithPrimary(ctx)
+	now := time.Now()
+	for _, tc := range []struct {
+		name string
+		gen  string
+		user *models.User
+		want models.ErrorType
+	}{
+		{"should let active admins in", "gen-1", &models.User{Id: "1", Roles: []string{"admin"}}, ""},
+		{"should reject revoked sessions", "gen-0", &models.User{Id: "1", Roles: []string{"admin"}}, models.ErrInvalidAccess},
+		{"should reject deleted accounts", "gen-1", &models.User{Id: "1", Roles: []string{"admin"}, DeletedAt: &now}, models.ErrInvalidAccess},
+		{"should reject disabled accounts", "gen-1", &models.User{Id: "1", Roles: []string{"admin"}, DisabledAt: &now}, models.ErrForbidden},
+		{"should reject accounts that lost the role", "gen-1", &models.User{Id: "1", Roles: []string{"user"}}, models.ErrForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := new(mocks.MockStore)
+			cache := new(mocks.MockCache)
+			cache.On("Get", "session_gen:1", mock.Anything).Run(setGeneration("gen-1")).Return(nil)
+			store.On("FindByID", primary, "1").Return(tc.user, nil)
+			authService := service.NewAuthService(store, cache, new(mocks.MockHasher), new(mocks.MockToken), new(mocks.MockMailer), models.EmailNormalizer{})
+			err := authService.CheckAccess(ctx, "1", tc.gen, "admin")
+			if tc.want == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var appErr *models.AppError
+			assert.ErrorAs(t, err, &appErr)
+			assert.Equal(t, tc.want, appErr.Type)
+		})
+	}
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	ctx := context.Background()
+	primary := ports.WithPrimary(ctx)
+	user := &models.User{Id: "1", Email: "jane@test.com", PasswordHash: "hashedPassword"}
+
+	t.Run("should change the password and revoke other sessions", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		cache := new(mocks.MockCache)
+		hasher := new(mocks.MockHasher)
+		token := new(mocks.MockToken)
+		store.On("FindByID", primary, "1").Return(user, nil)
+		hasher.On("Compare", "hashedPassword", "oldPassword1").Return(nil)
+		hasher.On("Hash", "newPassword1").Return("newHash", nil)
+		store.On("UpdatePassword", ctx, "1", "newHash").Return(nil)
+		store.On("AddAudit", ctx, mock.MatchedBy(func(e *models.AuditEntry) bool {
+			return e.Action == models.AuditPasswordChanged
+		})).Return(nil)
+		store.On("AddEvent", ctx, mock.MatchedBy(func(e *models.Event) bool {
+			return e.Type == models.EventPasswordChanged && e.AggregateId == "1"
+		})).Return(nil)
+		cache.On("Get", "sessions:1", mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(1).(*[]string) = []string{"session-a", "session-b"}
+		}).Return(nil).Once()
+		cache.On("Set", "session_gen:1", mock.Anything).Return(nil)
+		cache.On("Delete", "session-a").Return(nil)
+		cache.On("Delete", "session-b").Return(nil)
+		cache.On("Delete", "sessions:1").Return(nil)
+		cache.On("Get", "session_gen:1", mock.Anything).Run(setGeneration("gen-2")).Return(nil)
+		cache.On("Get", "sessions:1", mock.Anything).Return(assert.AnError)
+		cache.On("SetWithTTL", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		token.On("Generate", mock.Anything).Return("access-token", nil)
+		authService := service.NewAuthService(store, cache, hasher, token, new(mocks.MockMailer), models.EmailNormalizer{})
+		tokens, err := authService.ChangePassword(ctx, "1", "oldPassword1", "newPassword1")
+		assert.NoError(t, err)
+		assert.Equal(t, "access-token", tokens.AccessToken)
+		store.AssertExpectations(t)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("should fail without revoking sessions when the audit entry cannot be saved", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		cache := new(mocks.MockCache)
+		hasher := new(mocks.MockHasher)
+		store.On("FindByID", primary, "1").Return(user, nil)
+		hasher.On("Compare", "hashedPassword", "oldPassword1").Return(nil)
+		hasher.On("Hash", "newPassword1").Return("newHash", nil)
+		store.On("UpdatePassword", ctx, "1", "newHash").Return(nil)
+		store.On("AddAudit", ctx, mock.Anything).Return(assert.AnError)
+		authService := service.NewAuthService(store, cache, hasher, new(mocks.MockToken), new(mocks.MockMailer), models.EmailNormalizer{})
+		_, err := authService.ChangePassword(ctx, "1", "oldPassword1", "newPassword1")
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.True(t, appErr.IsInternal)
+		cache.AssertNotCalled(t, "Delete", mock.Anything)
+	})
+
+	t.Run("should reject an incorrect current password", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		hasher := new(mocks.MockHasher)
+		store.On("FindByID", primary, "1").Return(user, nil)
+		hasher.On("Compare", "hashedPassword", "wrong").Return(assert.AnError)
+		authService := service.NewAuthService(store, new(mocks.MockCache), hasher, new(mocks.MockToken), new(mocks.MockMailer), models.EmailNormalizer{})
+		_, err := authService.ChangePassword(ctx, "1", "wrong", "newPassword1")
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, models.ErrForbidden, appErr.Type)
+		store.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should enforce the password policy", func(t *testing.T) {
+		for _, password := range []string{"short1", "onlyletters", "12345678901", "jane12345"} {
+			store := new(mocks.MockStore)
+			hasher := new(mocks.MockHasher)
+			store.On("FindByID", primary, "1").Return(user, nil)
+			hasher.On("Compare", "hashedPassword", "oldPassword1").Return(nil)
+			authService := service.NewAuthService(store, new(mocks.MockCache), hasher, new(mocks.MockToken), new(mocks.MockMailer), models.EmailNormalizer{})
+			_, err := authService.ChangePassword(ctx, "1", "oldPassword1", password)
+			var appErr *models.AppError
+			assert.ErrorAs(t, err, &appErr, password)
+			assert.Equal(t, models.ErrInvalidBody, appErr.Type, password)
+		}
+	})
+}
+
+func TestAuthService_RequestEmailChange(t *testing.T) {
+	ctx := context.Background()
+	primary := ports.WithPrimary(ctx)
+	user := &models.User{Id: "1", Email: "old@test.com", PasswordHash: "hashedPassword"}
+
+	t.Run("should mail a confirmation to the new address and a notice to the old one", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		cache := new(mocks.MockCache)
+		hasher := new(mocks.MockHasher)
+		mailer := new(mocks.MockMailer)
+		store.On("FindByID", primary, "1").Return(user, nil)
+		hasher.On("Compare", "hashedPassword", "password1").Return(nil)
+		store.On("FindByEmail", ctx, "new@test.com").Return((*models.User)(nil), nil)
+		cache.On("SetWithTTL", mock.MatchedBy(func(key string) bool {
+			return strings.HasPrefix(key, "email_change:")
+		}), models.EmailChange{UserId: "1", Email: "new@test.com"}, 60*60*24).Return(nil)
+		mailer.On("Send", ctx, mock.MatchedBy(func(m *models.Mail) bool { return m.To == "new@test.com" })).Return(nil)
+		mailer.On("Send", ctx, mock.MatchedBy(func(m *models.Mail) bool { return m.To == "old@test.com" })).Return(nil)
+		store.On("AddAudit", ctx, mock.Anything).Return(nil)
+		authService := service.NewAuthService(store, cache, hasher, new(mocks.MockToken), mailer, models.EmailNormalizer{})
+		err := authService.RequestEmailChange(ctx, "1", "password1", "new@test.com")
+		assert.NoError(t, err)
+		cache.AssertExpectations(t)
+		mailer.AssertExpectations(t)
+		store.AssertNotCalled(t, "UpdateEmail", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should reject an address in use", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		hasher := new(mocks.MockHasher)
+		store.On("FindByID", primary, "1").Return(user, nil)
+		hasher.On("Compare", "hashedPassword", "password1").Return(nil)
+		store.On("FindByEmail", ctx, "taken@test.com").Return(&models.User{Id: "2"}, nil)
+		authService := service.NewAuthService(store, new(mocks.MockCache), hasher, new(mocks.MockToken), new(mocks.MockMailer), models.EmailNormalizer{})
+		err := authService.RequestEmailChange(ctx, "1", "password1", "taken@test.com")
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, models.ErrConflict, appErr.Type)
+	})
+}
+
+func TestAuthService_ConfirmEmailChange(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should switch the address and revoke sessions", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		cache := new(mocks.MockCache)
+		cache.On("Get", "email_change:token", mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(1).(*models.EmailChange) = models.EmailChange{UserId: "1", Email: "new@test.com"}
+		}).Return(nil)
+		store.On("FindByEmail", ctx, "new@test.com").Return((*models.User)(nil), nil)
+		store.On("UpdateEmail", ctx, "1", "new@test.com").Return(nil)
+		store.On("AddAudit", ctx, mock.Anything).Return(nil)
+		store.On("AddEvent", ctx, mock.MatchedBy(func(e *models.Event) bool {
+			return e.Type == models.EventEmailChanged && string(e.Payload) == `{"email":"new@test.com"}`
+		})).Return(nil)
+		cache.On("Delete", "email_change:token").Return(nil)
+		cache.On("Set", "session_gen:1", mock.Anything).Return(nil)
+		cache.On("Get", "sessions:1", mock.Anything).Return(assert.AnError)
+		cache.On("Delete", "sessions:1").Return(nil)
+		authService := service.NewAuthService(store, cache, new(mocks.MockHasher), new(mocks.MockToken), new(mocks.MockMailer), models.EmailNormalizer{})
+		assert.NoError(t, authService.ConfirmEmailChange(ctx, "token"))
+		store.AssertExpectations(t)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("should reject an unknown token", func(t *testing.T) {
+		cache := new(mocks.MockCache)
+		cache.On("Get", "email_change:bad", mock.Anything).Return(assert.AnError)
+		authService := service.NewAuthService(new(mocks.MockStore), cache, new(mocks.MockHasher), new(mocks.MockToken), new(mocks.MockMailer), models.EmailNormalizer{})
+		err := authService.ConfirmEmailChange(ctx, "bad")
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, models.ErrInvalidParam, appErr.Type)
+	})
+}
+
+func TestAuthService_DeleteAccount(t *testing.T) {
+	ctx := context.Background()
+	primary := ports.WithPrimary(ctx)
+
+	t.Run("should soft delete the account and revoke its sessions", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		cache := new(mocks.MockCache)
+		hasher := new(mocks.MockHasher)
+		mailer := new(mocks.MockMailer)
+		store.On("FindByID", primary, "1").Return(&models.User{Id: "1", Email: "user@test.com", PasswordHash: "hash"}, nil)
+		hasher.On("Compare", "hash", "password1").Return(nil)
+		store.On("SoftDelete", ctx, "1", mock.AnythingOfType("time.Time")).Return(nil)
+		cache.On("Set", "session_gen:1", mock.Anything).Return(nil)
+		cache.On("Get", "sessions:1", mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(1).(*[]string) = []string{"session-a"}
+		}).Return(nil)
+		cache.On("Delete", "session-a").Return(nil)
+		cache.On("Delete", "sessions:1").Return(nil)
+		store.On("AddAudit", ctx, mock.MatchedBy(func(e *models.AuditEntry) bool {
+			return e.Action == models.AuditAccountDeleted
+		})).Return(nil)
+		store.On("AddEvent", ctx, mock.MatchedBy(func(e *models.Event) bool {
+			return e.Type == models.EventUserDeleted && e.AggregateId == "1"
+		})).Return(nil)
+		mailer.On("Send", ctx, mock.Anything).Return(nil)
+		authService := service.NewAuthService(store, cache, hasher, new(mocks.MockToken), mailer, models.EmailNormalizer{})
+		assert.NoError(t, authService.DeleteAccount(ctx, "1", "password1"))
+		store.AssertExpectations(t)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("should not log in a deleted account", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		hasher := new(mocks.MockHasher)
+		deletedAt := time.Now()
+		store.On("FindByEmail", primary, "user@test.com").Return(&models.User{Id: "1", PasswordHash: "hash", DeletedAt: &deletedAt}, nil)
+		hasher.On("Compare", "hash", "password1").Return(nil)
+		authService := service.NewAuthService(store, new(mocks.MockCache), hasher, new(mocks.MockToken), new(mocks.MockMailer), models.EmailNormalizer{})
+		tokens, err := authService.Login(ctx, "user@test.com", "password1")
+		assert.Nil(t, tokens)
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, models.ErrInvalidAccess, appErr.Type)
+	})
+}
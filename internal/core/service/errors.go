@@ -0,0 +1,19 @@
+package service
+
+import (
+	"errors"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
+)
+
+// storeError maps an error of the store to an application error. A violated
+// constraint means the request conflicts with the data, e.g. with a row a
+// concurrent request wrote after it was checked; conflict describes it.
+func storeError(err error, conflict string) error {
+	var constraintErr *ports.ConstraintError
+	if errors.As(err, &constraintErr) {
+		return models.Conflict(conflict, err)
+	}
+	return models.Internal(err)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// relayBatch is how many events are claimed at once.
+	relayBatch = 50
+	// relayLease is how long claimed events are kept from other relays. A
+	// batch is left unfinished once half of it is over, the rest of the
+	// events are deferred and claimed again by the next batch.
+	relayLease = 2 * time.Minute
+	// maxRetryBackoff caps the wait before an event that failed to be
+	// published is retried.
+	maxRetryBackoff = time.Hour
+)
+
+func newUserEvent(eventType, userId string, payload models.UserEventPayload) *models.Event {
+	// A struct of strings always encodes.
+	data, _ := json.Marshal(payload)
+	return &models.Event{
+		Id:          uuid.NewString(),
+		Type:        eventType,
+		AggregateId: userId,
+		Payload:     data,
+		OccurredAt:  time.Now(),
+	}
+}
+
+type eventService struct {
+	store     ports.OutboxStore
+	publisher ports.Publisher
+	lease     time.Duration
+}
+
+func NewEventService(store ports.OutboxStore, publisher ports.Publisher) ports.EventService {
+	return &eventService{store, publisher, relayLease}
+}
+
+// RelayEvents publishes batches of events until none is due. An event is
+// marked published only once the publisher accepted it, so it is published
+// again if the relay stops in between. When an event fails, the later events
+// of its aggregate are deferred until it is retried. Every claimed event is
+// either processed or deferred, since a lease left to run out would block its
+// aggregate for the whole lease.
+func (e *eventService) RelayEvents(ctx context.Context) (int, error) {
+	published := 0
+	for ctx.Err() == nil {
+		claimed := time.Now()
+		events, err := e.store.ClaimEvents(ctx, claimed, e.lease, relayBatch)
+		if err != nil {
+			return published, models.Internal(err)
+		}
+		if len(events) == 0 {
+			break
+		}
+		// failed holds when the aggregates whose event failed are retried.
+		failed := map[string]time.Time{}
+		for i := range events {
+			event := &events[i]
+			availableAt, skip := failed[event.AggregateId]
+			if !skip && time.Since(claimed) > e.lease/2 {
+				availableAt, skip = time.Now(), true
+			}
+			if skip {
+				if err := e.store.DeferEvent(ctx, event.Id, availableAt); err != nil {
+					return published, models.Internal(err)
+				}
+				continue
+			}
+			if pubErr := e.publisher.Publish(ctx, event); pubErr != nil {
+				retryAt := time.Now().Add(retryBackoff(event.Attempts))
+				failed[event.AggregateId] = retryAt
+				slog.Warn("failed to publish event", "event", event.Id, "type", event.Type, "attempts", event.Attempts+1, "retry_at", retryAt, "error", pubErr.Error())
+				if err := e.store.MarkEventFailed(ctx, event.Id, retryAt, pubErr.Error()); err != nil {
+					return published, models.Internal(err)
+				}
+				continue
+			}
+			if err := e.store.MarkEventPublished(ctx, event.Id, time.Now()); err != nil {
+				return published, models.Internal(err)
+			}
+			published++
+		}
+	}
+	return published, nil
+}
+
+// retryBackoff doubles the wait after every failed attempt, from a second up
+// to maxRetryBackoff.
+func retryBackoff(attempts int) time.Duration {
+	if attempts >= 12 {
+		return maxRetryBackoff
+	}
+	return min(time.Second<<attempts, maxRetryBackoff)
+}
+
+func (e *eventService) PurgePublished(ctx context.Context, retention time.Duration) (int64, error) {
+	n, err := e.store.PurgePublishedEvents(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return 0, models.Internal(err)
+	}
+	return n, nil
+}
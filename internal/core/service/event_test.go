@@ -0,0 +1,103 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/service"
+	"go-web/tests/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEventService_RelayEvents(t *testing.T) {
+	ctx := context.Background()
+	claim := func(store *mocks.MockStore, events ...models.Event) {
+		store.On("ClaimEvents", ctx, mock.AnythingOfType("time.Time"), mock.Anything, mock.Anything).Return(events, nil).Once()
+	}
+	isEvent := func(id string) any {
+		return mock.MatchedBy(func(e *models.Event) bool { return e.Id == id })
+	}
+
+	t.Run("should publish the events in order and mark them published", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		publisher := new(mocks.MockPublisher)
+		claim(store, models.Event{Id: "a1", AggregateId: "a"}, models.Event{Id: "a2", AggregateId: "a"})
+		claim(store)
+		var order []string
+		publisher.On("Publish", ctx, mock.Anything).Run(func(args mock.Arguments) {
+			order = append(order, args.Get(1).(*models.Event).Id)
+		}).Return(nil)
+		store.On("MarkEventPublished", ctx, "a1", mock.AnythingOfType("time.Time")).Return(nil)
+		store.On("MarkEventPublished", ctx, "a2", mock.AnythingOfType("time.Time")).Return(nil)
+
+		n, err := service.NewEventService(store, publisher).RelayEvents(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+		assert.Equal(t, []string{"a1", "a2"}, order)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("should defer the later events of an aggregate whose event failed", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		publisher := new(mocks.MockPublisher)
+		claim(store,
+			models.Event{Id: "a1", AggregateId: "a", Attempts: 2},
+			models.Event{Id: "b1", AggregateId: "b"},
+			models.Event{Id: "a2", AggregateId: "a"},
+		)
+		claim(store)
+		publisher.On("Publish", ctx, isEvent("a1")).Return(assert.AnError)
+		publisher.On("Publish", ctx, isEvent("b1")).Return(nil)
+		var retryAt time.Time
+		store.On("MarkEventFailed", ctx, "a1", mock.MatchedBy(func(at time.Time) bool {
+			// The third attempt waits 4 seconds.
+			retryAt = at
+			wait := time.Until(at)
+			return wait > 3*time.Second && wait <= 4*time.Second
+		}), assert.AnError.Error()).Return(nil)
+		store.On("MarkEventPublished", ctx, "b1", mock.AnythingOfType("time.Time")).Return(nil)
+		// Left leased, a2 would block a for the whole lease instead.
+		store.On("DeferEvent", ctx, "a2", mock.MatchedBy(func(at time.Time) bool {
+			return at.Equal(retryAt)
+		})).Return(nil)
+
+		n, err := service.NewEventService(store, publisher).RelayEvents(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		publisher.AssertNotCalled(t, "Publish", ctx, isEvent("a2"))
+		store.AssertExpectations(t)
+	})
+
+	t.Run("should defer the events left when half of the lease is over", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		publisher := new(mocks.MockPublisher)
+		claim(store, models.Event{Id: "a1", AggregateId: "a"}, models.Event{Id: "b1", AggregateId: "b"})
+		claim(store)
+		publisher.On("Publish", ctx, isEvent("a1")).Run(func(mock.Arguments) {
+			time.Sleep(30 * time.Millisecond)
+		}).Return(nil)
+		store.On("MarkEventPublished", ctx, "a1", mock.AnythingOfType("time.Time")).Return(nil)
+		store.On("DeferEvent", ctx, "b1", mock.MatchedBy(func(at time.Time) bool {
+			return !at.After(time.Now())
+		})).Return(nil)
+
+		n, err := service.NewEventServiceWithLease(store, publisher, 40*time.Millisecond).RelayEvents(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		publisher.AssertNotCalled(t, "Publish", ctx, isEvent("b1"))
+		store.AssertExpectations(t)
+	})
+
+	t.Run("should fail when the outbox cannot be read", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		store.On("ClaimEvents", ctx, mock.Anything, mock.Anything, mock.Anything).Return([]models.Event(nil), assert.AnError)
+		_, err := service.NewEventService(store, new(mocks.MockPublisher)).RelayEvents(ctx)
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.True(t, appErr.IsInternal)
+	})
+}
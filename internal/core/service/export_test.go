@@ -0,0 +1,12 @@
+package service
+
+import (
+	"time"
+
+	"go-web/internal/core/ports"
+)
+
+// NewEventServiceWithLease lets tests reach the end of a lease quickly.
+func NewEventServiceWithLease(store ports.OutboxStore, publisher ports.Publisher, lease time.Duration) ports.EventService {
+	return &eventService{store, publisher, lease}
+}
@@ -0,0 +1,15 @@
+package service
+
+import "go-web/internal/core/models"
+
+// paginate turns the items read for a page into a Page. Listings read one item
+// more than limit: when it is there, another page follows and starts after the
+// last item kept.
+func paginate[T any](items []T, limit int, cursor func(*T) models.Cursor) *models.Page[T] {
+	if len(items) <= limit {
+		return &models.Page[T]{Items: items}
+	}
+	items = items[:limit]
+	next := cursor(&items[limit-1])
+	return &models.Page[T]{Items: items, Next: &next}
+}
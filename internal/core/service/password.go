@@ -0,0 +1,35 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"go-web/internal/core/models"
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores everything after 72 bytes.
+	maxPasswordLength = 72
+)
+
+// checkPasswordPolicy rejects passwords that are too short or too long, that
+// lack a letter or a digit, or that contain the user's email name.
+func checkPasswordPolicy(password, email string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return models.InvalidBody("Password must be between 8 and 72 characters", nil)
+	}
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		hasLetter = hasLetter || unicode.IsLetter(r)
+		hasDigit = hasDigit || unicode.IsDigit(r)
+	}
+	if !hasLetter || !hasDigit {
+		return models.InvalidBody("Password must contain a letter and a digit", nil)
+	}
+	name, _, _ := strings.Cut(strings.ToLower(email), "@")
+	if len(name) >= 3 && strings.Contains(strings.ToLower(password), name) {
+		return models.InvalidBody("Password must not contain your email", nil)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"slices"
+	"time"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
+	"go-web/internal/shared"
+)
+
+// sessionTTL is the lifetime of a refresh token, in seconds.
+const sessionTTL = 60 * 60 * 24 * 7 // 7 days
+
+// sessionsKey indexes the refresh tokens of a user so that they can be listed
+// and deleted at once. Concurrent sessions may drop each other from the index,
+// so revocation relies on the session generation instead.
+func sessionsKey(userId string) string {
+	return "sessions:" + userId
+}
+
+// generationKey holds the session generation of a user. Refresh tokens carry
+// the generation they were issued under and are rejected once it changed.
+// The generation never expires so that a revocation cannot be undone by it
+// expiring; an evicted generation only ends every session of the user.
+func generationKey(userId string) string {
+	return "session_gen:" + userId
+}
+
+// sessionGeneration returns the current session generation of the user,
+// starting one when there is none. Concurrent callers agree on the first one
+// stored.
+func sessionGeneration(cache ports.Cache, userId string) (string, error) {
+	var gen string
+	if err := cache.Get(generationKey(userId), &gen); err == nil && gen != "" {
+		return gen, nil
+	}
+	if _, err := cache.Add(generationKey(userId), shared.RandString(16), 0); err != nil {
+		return "", err
+	}
+	if err := cache.Get(generationKey(userId), &gen); err != nil {
+		return "", err
+	}
+	return gen, nil
+}
+
+// newSession issues an access token and a refresh token for user, under the
+// current session generation of the user.
+func (a *authService) newSession(user models.RefreshUser) (*models.AuthTokens, error) {
+	gen, err := sessionGeneration(a.cache, user.Id)
+	if err != nil {
+		return nil, models.Internal(err)
+	}
+	user.Generation = gen
+	return a.issueSession(user)
+}
+
+// issueSession issues the tokens under user.Generation. Refresh keeps the
+// generation of the token it replaces, so that a revocation racing it also
+// revokes the new token.
+func (a *authService) issueSession(user models.RefreshUser) (*models.AuthTokens, error) {
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	claims := map[string]interface{}{
+		"sub":   user.Id,
+		"email": user.Email,
+		"roles": user.Roles,
+		"gen":   user.Generation,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute * 15).Unix(),
+		"jti":   shared.RandString(8),
+	}
+	accessToken, err := a.token.Generate(claims)
+	if err != nil {
+		return nil, models.Internal(err)
+	}
+	refreshToken := shared.RandString(16)
+	if err := a.cache.SetWithTTL(refreshToken, user, sessionTTL); err != nil {
+		return nil, models.Internal(err)
+	}
+	sessions := listSessions(a.cache, user.Id)
+	if err := a.cache.SetWithTTL(sessionsKey(user.Id), append(sessions, refreshToken), sessionTTL); err != nil {
+		return nil, models.Internal(err)
+	}
+	return &models.AuthTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// listSessions returns the refresh tokens of the user. A missing index means
+// the user has no session.
+func listSessions(cache ports.Cache, userId string) []string {
+	var tokens []string
+	if err := cache.Get(sessionsKey(userId), &tokens); err != nil {
+		return nil
+	}
+	return tokens
+}
+
+func (a *authService) endSession(userId, refreshToken string) error {
+	if err := a.cache.Delete(refreshToken); err != nil {
+		return err
+	}
+	sessions := slices.DeleteFunc(listSessions(a.cache, userId), func(t string) bool { return t == refreshToken })
+	return a.cache.SetWithTTL(sessionsKey(userId), sessions, sessionTTL)
+}
+
+// revokeSessions starts a new session generation, which invalidates every
+// refresh token of the user, then deletes the tokens it knows of. Access
+// tokens that were already issued stay valid until they expire, except on the
+// routes guarded by CheckAccess.
+func revokeSessions(cache ports.Cache, userId string) error {
+	if err := cache.Set(generationKey(userId), shared.RandString(16)); err != nil {
+		return err
+	}
+	for _, token := range listSessions(cache, userId) {
+		//nolint:errcheck
+		cache.Delete(token)
+	}
+	return cache.Delete(sessionsKey(userId))
+}
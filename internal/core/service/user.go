@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
+)
+
+type userService struct {
+	store ports.Store
+	cache ports.Cache
+}
+
+func NewUserService(store ports.Store, cache ports.Cache) ports.UserService {
+	return &userService{store, cache}
+}
+
+func (u *userService) Get(ctx context.Context, id string) (*models.User, error) {
+	user, err := u.store.FindByID(ctx, id)
+	if err != nil {
+		return nil, models.Internal(err)
+	}
+	if user == nil || user.DeletedAt != nil {
+		return nil, models.NotFound("User not found", nil)
+	}
+	return user, nil
+}
+
+// UpdateProfile applies update to user. The store only saves it if user is
+// still the latest version, so concurrent edits fail instead of being lost.
+func (u *userService) UpdateProfile(ctx context.Context, user *models.User, update models.ProfileUpdate) (*models.User, error) {
+	changed := *user
+	if update.DisplayName != nil {
+		changed.DisplayName = *update.DisplayName
+	}
+	if update.AvatarURL != nil {
+		changed.AvatarURL = *update.AvatarURL
+	}
+	if update.Locale != nil {
+		changed.Locale = *update.Locale
+	}
+	if update.Timezone != nil {
+		changed.Timezone = *update.Timezone
+	}
+	saved, err := u.store.Update(ctx, &changed)
+	if err != nil {
+		return nil, models.Internal(err)
+	}
+	if saved == nil {
+		return nil, models.PreconditionFailed("Resource has been modified", nil)
+	}
+	return saved, nil
+}
+
+// Export collects the profile, the active sessions and the audit log of the
+// user. Refresh tokens are identified by a hash, never by their value.
+func (u *userService) Export(ctx context.Context, id string) (*models.UserExport, error) {
+	user, err := u.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	audit, err := u.store.ListAudit(ctx, id)
+	if err != nil {
+		return nil, models.Internal(err)
+	}
+	export := &models.UserExport{User: user, Audit: audit}
+	if u.cache == nil {
+		return export, nil
+	}
+	for _, token := range listSessions(u.cache, id) {
+		var refreshUser models.RefreshUser
+		if err := u.cache.Get(token, &refreshUser); err != nil {
+			continue
+		}
+		sum := sha256.Sum256([]byte(token))
+		export.Sessions = append(export.Sessions, models.Session{
+			Id:        hex.EncodeToString(sum[:8]),
+			CreatedAt: refreshUser.CreatedAt,
+		})
+	}
+	return export, nil
+}
+
+// PurgeDeleted removes the accounts deleted more than grace ago.
+func (u *userService) PurgeDeleted(ctx context.Context, grace time.Duration) (int64, error) {
+	n, err := u.store.PurgeDeleted(ctx, time.Now().Add(-grace))
+	if err != nil {
+		return 0, models.Internal(err)
+	}
+	return n, nil
+}
@@ -0,0 +1,86 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/service"
+	"go-web/tests/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUserService_Get(t *testing.T) {
+	ctx := context.Background()
+	t.Run("should return the user", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		store.On("FindByID", ctx, "1").Return(&models.User{Id: "1", Email: "user@test.com"}, nil)
+		user, err := service.NewUserService(store, new(mocks.MockCache)).Get(ctx, "1")
+		assert.NoError(t, err)
+		assert.Equal(t, "user@test.com", user.Email)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("should return not found for a missing user", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		store.On("FindByID", ctx, "1").Return((*models.User)(nil), nil)
+		user, err := service.NewUserService(store, new(mocks.MockCache)).Get(ctx, "1")
+		assert.Nil(t, user)
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, models.ErrNotFound, appErr.Type)
+	})
+
+	t.Run("should wrap store errors as internal", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		store.On("FindByID", ctx, "1").Return((*models.User)(nil), errors.New("db down"))
+		_, err := service.NewUserService(store, new(mocks.MockCache)).Get(ctx, "1")
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.True(t, appErr.IsInternal)
+	})
+}
+
+func TestUserService_UpdateProfile(t *testing.T) {
+	ctx := context.Background()
+	version := time.Now()
+	name := "Jane"
+
+	t.Run("should save the changed fields", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		current := &models.User{Id: "1", DisplayName: "Old", UpdatedAt: version}
+		store.On("Update", ctx, mock.MatchedBy(func(u *models.User) bool {
+			return u.DisplayName == name && u.UpdatedAt.Equal(version)
+		})).Return(&models.User{Id: "1", DisplayName: name, UpdatedAt: version.Add(time.Second)}, nil)
+		user, err := service.NewUserService(store, new(mocks.MockCache)).UpdateProfile(ctx, current, models.ProfileUpdate{DisplayName: &name})
+		assert.NoError(t, err)
+		assert.Equal(t, name, user.DisplayName)
+		assert.Equal(t, "Old", current.DisplayName)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("should fail when the user was modified concurrently", func(t *testing.T) {
+		store := new(mocks.MockStore)
+		store.On("Update", ctx, mock.Anything).Return((*models.User)(nil), nil)
+		_, err := service.NewUserService(store, new(mocks.MockCache)).UpdateProfile(ctx, &models.User{Id: "1"}, models.ProfileUpdate{DisplayName: &name})
+		var appErr *models.AppError
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, models.ErrPrecondition, appErr.Type)
+	})
+}
+
+func TestUserService_PurgeDeleted(t *testing.T) {
+	ctx := context.Background()
+	store := new(mocks.MockStore)
+	store.On("PurgeDeleted", ctx, mock.MatchedBy(func(before time.Time) bool {
+		return time.Since(before) >= 24*time.Hour
+	})).Return(int64(2), nil)
+	n, err := service.NewUserService(store, new(mocks.MockCache)).PurgeDeleted(ctx, 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	store.AssertExpectations(t)
+}
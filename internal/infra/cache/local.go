@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"sync"
+	"time"
+
+	"go-web/internal/core/ports"
+)
+
+var ErrCacheMiss = errors.New("cache: miss")
+
+// sweepInterval is how often writes also drop the expired items, which are
+// otherwise only dropped when read.
+const sweepInterval = time.Minute
+
+type localItem struct {
+	value   []byte
+	expires time.Time
+}
+
+func (i localItem) expired(now time.Time) bool {
+	return !i.expires.IsZero() && !now.Before(i.expires)
+}
+
+// localCache keeps the items in the memory of the process, for development and
+// tests. Values are gob encoded like in the other caches, so that callers get
+// copies.
+type localCache struct {
+	mu        sync.Mutex
+	items     map[string]localItem
+	lastSweep time.Time
+}
+
+func NewLocalCache() ports.Cache {
+	return &localCache{items: map[string]localItem{}, lastSweep: time.Now()}
+}
+
+func (c *localCache) Set(key string, value interface{}) error {
+	return c.SetWithTTL(key, value, 0)
+}
+
+// SetWithTTL keeps the item for ttl seconds, or forever when ttl is 0.
+func (c *localCache) SetWithTTL(key string, value interface{}, ttl int) error {
+	item, err := newLocalItem(value, ttl)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep()
+	c.items[key] = item
+	return nil
+}
+
+func (c *localCache) Add(key string, value interface{}, ttl int) (bool, error) {
+	item, err := newLocalItem(value, ttl)
+	if err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep()
+	if existing, ok := c.items[key]; ok && !existing.expired(time.Now()) {
+		return false, nil
+	}
+	c.items[key] = item
+	return true, nil
+}
+
+func (c *localCache) Get(key string, value interface{}) error {
+	c.mu.Lock()
+	item, ok := c.items[key]
+	if ok && item.expired(time.Now()) {
+		delete(c.items, key)
+		ok = false
+	}
+	c.mu.Unlock()
+	if !ok {
+		return ErrCacheMiss
+	}
+	return gob.NewDecoder(bytes.NewReader(item.value)).Decode(value)
+}
+
+func (c *localCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, key)
+	return nil
+}
+
+func newLocalItem(value interface{}, ttl int) (localItem, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return localItem{}, err
+	}
+	item := localItem{value: buf.Bytes()}
+	if ttl > 0 {
+		item.expires = time.Now().Add(time.Duration(ttl) * time.Second)
+	}
+	return item, nil
+}
+
+// sweep must be called with the lock held.
+func (c *localCache) sweep() {
+	now := time.Now()
+	if now.Sub(c.lastSweep) < sweepInterval {
+		return
+	}
+	c.lastSweep = now
+	for key, item := range c.items {
+		if item.expired(now) {
+			delete(c.items, key)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"bytes"
 	"encoding/gob"
+	"errors"
 
 	"go-web/internal/core/ports"
 
@@ -52,6 +53,21 @@ func (c *memCache) SetWithTTL(key string, value interface{}, ttl int) error {
 	return c.client.Set(&memcache.Item{Key: key, Value: buf.Bytes(), Expiration: int32(ttl)})
 }
 
+func (c *memCache) Add(key string, value interface{}, ttl int) (bool, error) {
+	if c == nil {
+		return true, nil
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return false, err
+	}
+	err := c.client.Add(&memcache.Item{Key: key, Value: buf.Bytes(), Expiration: int32(ttl)})
+	if errors.Is(err, memcache.ErrNotStored) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 func (c *memCache) Delete(key string) error {
 	if c == nil {
 		return nil
@@ -61,6 +61,17 @@ func (c *redisCache) SetWithTTL(key string, value interface{}, ttl int) error {
 	return c.client.Set(c.ctx, key, buf.Bytes(), time.Duration(ttl)*time.Second).Err()
 }
 
+func (c *redisCache) Add(key string, value interface{}, ttl int) (bool, error) {
+	if c == nil {
+		return true, nil
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return false, err
+	}
+	return c.client.SetNX(c.ctx, key, buf.Bytes(), time.Duration(ttl)*time.Second).Result()
+}
+
 func (c *redisCache) Delete(key string) error {
 	if c == nil {
 		return nil
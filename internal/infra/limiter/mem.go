@@ -43,6 +43,18 @@ func (l *memLimiter) getVisitor(key string) *rate.Limiter {
 	return v.limiter
 }
 
+func (l *memLimiter) SetLimit(limit float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.r = rate.Limit(limit)
+	l.b = burst
+	for _, v := range l.visitors {
+		v.limiter.SetLimit(l.r)
+		v.limiter.SetBurst(l.b)
+	}
+}
+
 func (l *memLimiter) Allow(ctx context.Context, key string) (bool, error) {
 	limiter := l.getVisitor(key)
 	return limiter.Allow(), nil
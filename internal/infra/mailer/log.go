@@ -0,0 +1,22 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
+)
+
+type logMailer struct{}
+
+// NewLogMailer writes outgoing mail to the log instead of delivering it. It
+// is meant for development until a real transport is configured.
+func NewLogMailer() ports.Mailer {
+	return &logMailer{}
+}
+
+func (m *logMailer) Send(ctx context.Context, mail *models.Mail) error {
+	slog.InfoContext(ctx, "mail sent", "to", mail.To, "subject", mail.Subject, "body", mail.Body)
+	return nil
+}
@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+
+	"go-web/internal/core/models"
+)
+
+// MemMailer keeps outgoing mail in memory so tests can read it back.
+type MemMailer struct {
+	mu   sync.Mutex
+	sent []models.Mail
+}
+
+func NewMemMailer() *MemMailer {
+	return &MemMailer{}
+}
+
+func (m *MemMailer) Send(ctx context.Context, mail *models.Mail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, *mail)
+	return nil
+}
+
+// Sent returns the mail delivered to the given address, oldest first.
+func (m *MemMailer) Sent(to string) []models.Mail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []models.Mail
+	for _, mail := range m.sent {
+		if mail.To == to {
+			out = append(out, mail)
+		}
+	}
+	return out
+}
@@ -0,0 +1,28 @@
+package publisher
+
+import (
+	"context"
+	"log/slog"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
+)
+
+type logPublisher struct{}
+
+// NewLogPublisher writes the events to the log instead of delivering them. It
+// is meant for development until a real transport is configured.
+func NewLogPublisher() ports.Publisher {
+	return &logPublisher{}
+}
+
+func (p *logPublisher) Publish(ctx context.Context, event *models.Event) error {
+	slog.InfoContext(ctx, "event published",
+		"id", event.Id,
+		"type", event.Type,
+		"aggregate", event.AggregateId,
+		"seq", event.Seq,
+		"payload", string(event.Payload),
+	)
+	return nil
+}
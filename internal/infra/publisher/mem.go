@@ -0,0 +1,39 @@
+package publisher
+
+import (
+	"context"
+	"sync"
+
+	"go-web/internal/core/models"
+)
+
+// MemPublisher keeps the published events in memory so tests can read them
+// back.
+type MemPublisher struct {
+	mu        sync.Mutex
+	published []models.Event
+}
+
+func NewMemPublisher() *MemPublisher {
+	return &MemPublisher{}
+}
+
+func (p *MemPublisher) Publish(ctx context.Context, event *models.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.published = append(p.published, *event)
+	return nil
+}
+
+// Published returns the events of the given aggregate, oldest first.
+func (p *MemPublisher) Published(aggregateId string) []models.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var out []models.Event
+	for _, e := range p.published {
+		if e.AggregateId == aggregateId {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,29 @@
+package publisher
+
+import (
+	"encoding/json"
+	"time"
+
+	"go-web/internal/core/models"
+)
+
+// message is how an event is sent outside of the application.
+type message struct {
+	Id          string          `json:"id"`
+	Type        string          `json:"type"`
+	AggregateId string          `json:"aggregateId"`
+	Seq         int64           `json:"seq"`
+	OccurredAt  time.Time       `json:"occurredAt"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+func newMessage(event *models.Event) message {
+	return message{
+		Id:          event.Id,
+		Type:        event.Type,
+		AggregateId: event.AggregateId,
+		Seq:         event.Seq,
+		OccurredAt:  event.OccurredAt,
+		Payload:     event.Payload,
+	}
+}
@@ -0,0 +1,62 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
+)
+
+type webhookPublisher struct {
+	url    string
+	secret func() string
+	client *http.Client
+}
+
+// NewWebhookPublisher posts every event as JSON to url. The receiver must
+// answer with a 2xx status, anything else is a failed delivery. When secret
+// returns a key, the body is signed with HMAC-SHA256 in the X-Signature
+// header, as "sha256=<hex>", so the receiver can check where it comes from.
+func NewWebhookPublisher(url string, secret func() string, timeout time.Duration) ports.Publisher {
+	return &webhookPublisher{url: url, secret: secret, client: &http.Client{Timeout: timeout}}
+}
+
+func (p *webhookPublisher) Publish(ctx context.Context, event *models.Event) error {
+	body, err := json.Marshal(newMessage(event))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Id", event.Id)
+	req.Header.Set("X-Event-Type", event.Type)
+	if key := p.secret(); key != "" {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write(body)
+		req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	//nolint:errcheck
+	defer res.Body.Close()
+	//nolint:errcheck
+	io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", res.Status)
+	}
+	return nil
+}
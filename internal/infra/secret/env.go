@@ -0,0 +1,37 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go-web/internal/core/ports"
+)
+
+type envProvider struct{}
+
+// NewEnvProvider resolves a secret named "jwt_secret" from JWT_SECRET_FILE when
+// set, falling back to the JWT_SECRET variable itself.
+func NewEnvProvider() ports.SecretProvider {
+	return &envProvider{}
+}
+
+func (p *envProvider) Get(ctx context.Context, name string) (string, error) {
+	key := strings.ToUpper(name)
+	if path, exist := os.LookupEnv(key + "_FILE"); exist {
+		return readSecretFile(path)
+	}
+	if val, exist := os.LookupEnv(key); exist {
+		return val, nil
+	}
+	return "", fmt.Errorf("secret %s: %w", name, ports.ErrSecretNotFound)
+}
+
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,33 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"go-web/internal/core/ports"
+)
+
+type fileProvider struct {
+	dir string
+}
+
+// NewFileProvider reads each secret from a file of the same name inside dir,
+// which is how Docker and Kubernetes mount secrets (e.g. /run/secrets).
+func NewFileProvider(dir string) ports.SecretProvider {
+	return &fileProvider{dir: dir}
+}
+
+func (p *fileProvider) Get(ctx context.Context, name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+	val, err := readSecretFile(filepath.Join(p.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("secret %s: %w", name, ports.ErrSecretNotFound)
+	}
+	return val, err
+}
@@ -0,0 +1,98 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go-web/internal/core/ports"
+)
+
+const (
+	JwtSecret     = "jwt_secret"
+	StorePassword = "store_password"
+	WebhookSecret = "webhook_secret"
+)
+
+// Refresher caches secrets from a provider and re-reads them periodically so
+// rotated values are picked up without a restart. Secrets missing from the
+// provider resolve to their fallback value.
+type Refresher struct {
+	provider  ports.SecretProvider
+	interval  time.Duration
+	fallbacks map[string]string
+
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+func NewRefresher(provider ports.SecretProvider, interval time.Duration, fallbacks map[string]string) *Refresher {
+	return &Refresher{
+		provider:  provider,
+		interval:  interval,
+		fallbacks: fallbacks,
+		values:    make(map[string]string, len(fallbacks)),
+	}
+}
+
+// Load reads every secret once and fails if any of them cannot be resolved.
+func (r *Refresher) Load(ctx context.Context) error {
+	var errs []error
+	for name := range r.fallbacks {
+		if _, err := r.refresh(ctx, name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run re-reads the secrets on every interval until ctx is done. Failed reads
+// keep the previous value.
+func (r *Refresher) Run(ctx context.Context) {
+	if r.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for name := range r.fallbacks {
+				changed, err := r.refresh(ctx, name)
+				if err != nil {
+					slog.Warn("failed to refresh secret", "name", name, "error", err.Error())
+				} else if changed {
+					slog.Info("secret rotated", "name", name)
+				}
+			}
+		}
+	}
+}
+
+func (r *Refresher) Get(name string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if val, ok := r.values[name]; ok {
+		return val
+	}
+	return r.fallbacks[name]
+}
+
+func (r *Refresher) refresh(ctx context.Context, name string) (bool, error) {
+	val, err := r.provider.Get(ctx, name)
+	if errors.Is(err, ports.ErrSecretNotFound) {
+		val, err = r.fallbacks[name], nil
+	}
+	if err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old, ok := r.values[name]
+	r.values[name] = val
+	return ok && old != val, nil
+}
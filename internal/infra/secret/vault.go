@@ -0,0 +1,69 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-web/internal/core/ports"
+)
+
+type vaultProvider struct {
+	client *http.Client
+	addr   string
+	token  string
+	mount  string
+	path   string
+}
+
+// NewVaultProvider reads secrets from a single entry of a Vault-style KV v2
+// engine, i.e. GET {addr}/v1/{mount}/data/{path}. Each secret name is a key of
+// that entry.
+func NewVaultProvider(addr, token, mount, path string) ports.SecretProvider {
+	return &vaultProvider{
+		client: &http.Client{Timeout: 5 * time.Second},
+		addr:   strings.TrimRight(addr, "/"),
+		token:  token,
+		mount:  strings.Trim(mount, "/"),
+		path:   strings.Trim(path, "/"),
+	}
+}
+
+type vaultResponse struct {
+	Data struct {
+		Data map[string]string `json:"data"`
+	} `json:"data"`
+}
+
+func (p *vaultProvider) Get(ctx context.Context, name string) (string, error) {
+	url := fmt.Sprintf("%s/v1/%s/data/%s", p.addr, p.mount, p.path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	res, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request: %w", err)
+	}
+	//nolint:errcheck
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("secret %s: %w", name, ports.ErrSecretNotFound)
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault request: unexpected status %d", res.StatusCode)
+	}
+	var body vaultResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("vault response: %w", err)
+	}
+	val, ok := body.Data.Data[name]
+	if !ok {
+		return "", fmt.Errorf("secret %s: %w", name, ports.ErrSecretNotFound)
+	}
+	return val, nil
+}
@@ -0,0 +1,89 @@
+package secret_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go-web/internal/core/ports"
+	"go-web/internal/infra/secret"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeVault struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func (f *fakeVault) set(key, val string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data[key] = val
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Vault-Token") != "root" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if r.URL.Path != "/v1/secret/data/go-web" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	//nolint:errcheck
+	json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"data": f.data}})
+}
+
+func TestVaultProvider(t *testing.T) {
+	ctx := context.Background()
+	vault := &fakeVault{data: map[string]string{secret.JwtSecret: "vault-secret"}}
+	ts := httptest.NewServer(vault)
+	defer ts.Close()
+
+	t.Run("should read a key of the secret entry", func(t *testing.T) {
+		p := secret.NewVaultProvider(ts.URL, "root", "secret", "go-web")
+		val, err := p.Get(ctx, secret.JwtSecret)
+		require.NoError(t, err)
+		assert.Equal(t, "vault-secret", val)
+	})
+
+	t.Run("should report missing keys as not found", func(t *testing.T) {
+		p := secret.NewVaultProvider(ts.URL, "root", "secret", "go-web")
+		_, err := p.Get(ctx, secret.StorePassword)
+		assert.ErrorIs(t, err, ports.ErrSecretNotFound)
+	})
+
+	t.Run("should fail with an invalid token", func(t *testing.T) {
+		p := secret.NewVaultProvider(ts.URL, "wrong", "secret", "go-web")
+		_, err := p.Get(ctx, secret.JwtSecret)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ports.ErrSecretNotFound)
+	})
+
+	t.Run("should pick up rotated secrets on refresh", func(t *testing.T) {
+		p := secret.NewVaultProvider(ts.URL, "root", "secret", "go-web")
+		r := secret.NewRefresher(p, 10*time.Millisecond, map[string]string{
+			secret.JwtSecret:     "fallback",
+			secret.StorePassword: "fallback",
+		})
+		require.NoError(t, r.Load(ctx))
+		assert.Equal(t, "vault-secret", r.Get(secret.JwtSecret))
+		assert.Equal(t, "fallback", r.Get(secret.StorePassword))
+
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go r.Run(runCtx)
+		vault.set(secret.JwtSecret, "rotated-secret")
+		assert.Eventually(t, func() bool {
+			return r.Get(secret.JwtSecret) == "rotated-secret"
+		}, time.Second, 10*time.Millisecond)
+	})
+}
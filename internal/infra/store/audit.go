@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"go-web/internal/core/models"
+)
+
+func (p *pgStore) AddAudit(ctx context.Context, entry *models.AuditEntry) error {
+	query := `
+		INSERT INTO audit_entries (id, user_id, action, detail, created_at)
+		VALUES ($1, $2, $3, $4, $5);
+	`
+	_, err := p.writer("AddAudit").ExecContext(ctx, query, entry.Id, entry.UserId, entry.Action, entry.Detail, entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("store.AddAudit: %w", translateError(err))
+	}
+	return nil
+}
+
+func (p *pgStore) ListAudit(ctx context.Context, userId string) ([]models.AuditEntry, error) {
+	query := `
+		SELECT id, user_id, action, detail, created_at
+		FROM audit_entries
+		WHERE user_id = $1
+		ORDER BY created_at;
+	`
+	rows, err := p.reader(ctx, "ListAudit").QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("store.ListAudit: %w", err)
+	}
+	//nolint:errcheck
+	defer rows.Close()
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.Id, &e.UserId, &e.Action, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("store.ListAudit: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store.ListAudit: %w", err)
+	}
+	return entries, nil
+}
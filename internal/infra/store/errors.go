@@ -0,0 +1,31 @@
+package store
+
+import (
+	"errors"
+
+	"go-web/internal/core/ports"
+
+	"github.com/lib/pq"
+)
+
+// constraintKinds maps the SQLSTATE of constraint violations to their kind.
+var constraintKinds = map[pq.ErrorCode]error{
+	"23505": ports.ErrUniqueViolation,
+	"23503": ports.ErrForeignKeyViolation,
+	"23514": ports.ErrCheckViolation,
+}
+
+// translateError turns constraint violations reported by Postgres into
+// ports.ConstraintError, so that callers need not know the driver. Other
+// errors are returned as they are.
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	kind, ok := constraintKinds[pqErr.Code]
+	if !ok {
+		return err
+	}
+	return &ports.ConstraintError{Kind: kind, Constraint: pqErr.Constraint, Err: err}
+}
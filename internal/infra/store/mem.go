@@ -0,0 +1,486 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
+)
+
+// memStore keeps everything in memory, for development and tests. It enforces
+// the same constraints as the database schema. Transactions hold an exclusive
+// lock, so they run one at a time and never fail to serialize.
+type memStore struct {
+	mu   *sync.RWMutex
+	data *memData
+	// inTx is set on the store handed to the function of WithTx, which already
+	// holds the lock.
+	inTx bool
+}
+
+type memData struct {
+	users  map[string]models.User
+	audit  []models.AuditEntry
+	events []memEvent
+	seq    int64
+}
+
+// memEvent is an event of the outbox, with the columns of outbox_events.
+type memEvent struct {
+	models.Event
+	availableAt time.Time
+	publishedAt *time.Time
+	lastError   string
+}
+
+func NewMemStore() ports.Store {
+	return &memStore{mu: new(sync.RWMutex), data: &memData{users: map[string]models.User{}}}
+}
+
+func (d *memData) clone() *memData {
+	c := &memData{
+		users:  make(map[string]models.User, len(d.users)),
+		audit:  slices.Clone(d.audit),
+		events: slices.Clone(d.events),
+		seq:    d.seq,
+	}
+	for id, u := range d.users {
+		u.Roles = slices.Clone(u.Roles)
+		c.users[id] = u
+	}
+	return c
+}
+
+func (m *memStore) rlock() func() {
+	if m.inTx {
+		return func() {}
+	}
+	m.mu.RLock()
+	return m.mu.RUnlock
+}
+
+func (m *memStore) lock() func() {
+	if m.inTx {
+		return func() {}
+	}
+	m.mu.Lock()
+	return m.mu.Unlock
+}
+
+// memNow mimics the microsecond precision of the database.
+func memNow() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+func (m *memStore) WithTx(ctx context.Context, fn func(tx ports.Store) error) (err error) {
+	if m.inTx {
+		return fn(m)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("store.WithTx: %w", err)
+	}
+	snapshot := m.data.clone()
+	defer func() {
+		if r := recover(); r != nil {
+			*m.data = *snapshot
+			panic(r)
+		}
+		if err != nil {
+			*m.data = *snapshot
+		}
+	}()
+	return fn(&memStore{mu: m.mu, data: m.data, inTx: true})
+}
+
+// get returns a copy of the user, so that callers cannot change the stored one.
+func (m *memStore) get(id string) (*models.User, bool) {
+	u, ok := m.data.users[id]
+	if !ok {
+		return nil, false
+	}
+	u.Roles = slices.Clone(u.Roles)
+	return &u, true
+}
+
+// update applies change to the user if it exists.
+func (m *memStore) update(id string, change func(u *models.User)) {
+	defer m.lock()()
+	u, ok := m.data.users[id]
+	if !ok {
+		return
+	}
+	change(&u)
+	m.data.users[id] = u
+}
+
+// checkEmail enforces the unique index on lower(email).
+func (m *memStore) checkEmail(id, email string) error {
+	for _, u := range m.data.users {
+		if u.Id != id && strings.EqualFold(u.Email, email) {
+			return &ports.ConstraintError{Kind: ports.ErrUniqueViolation, Constraint: "users_email_lower_key"}
+		}
+	}
+	return nil
+}
+
+func (m *memStore) Create(ctx context.Context, user *models.User) (*models.User, error) {
+	defer m.lock()()
+	if _, ok := m.data.users[user.Id]; ok {
+		return nil, fmt.Errorf("store.Create: %w", &ports.ConstraintError{Kind: ports.ErrUniqueViolation, Constraint: "users_pkey"})
+	}
+	if err := m.checkEmail(user.Id, user.Email); err != nil {
+		return nil, fmt.Errorf("store.Create: %w", err)
+	}
+	now := memNow()
+	m.data.users[user.Id] = models.User{
+		Id:           user.Id,
+		Email:        user.Email,
+		PasswordHash: user.PasswordHash,
+		Roles:        []string{"user"},
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	u, _ := m.get(user.Id)
+	return u, nil
+}
+
+func (m *memStore) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	defer m.rlock()()
+	for id, u := range m.data.users {
+		if strings.EqualFold(u.Email, email) {
+			found, _ := m.get(id)
+			return found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memStore) FindByID(ctx context.Context, id string) (*models.User, error) {
+	defer m.rlock()()
+	u, _ := m.get(id)
+	return u, nil
+}
+
+func (m *memStore) Update(ctx context.Context, user *models.User) (*models.User, error) {
+	defer m.lock()()
+	u, ok := m.data.users[user.Id]
+	if !ok || !u.UpdatedAt.Equal(user.UpdatedAt) {
+		return nil, nil
+	}
+	u.DisplayName = user.DisplayName
+	u.AvatarURL = user.AvatarURL
+	u.Locale = user.Locale
+	u.Timezone = user.Timezone
+	u.UpdatedAt = memNow()
+	m.data.users[u.Id] = u
+	updated, _ := m.get(u.Id)
+	return updated, nil
+}
+
+func (m *memStore) UpdateLastLogin(ctx context.Context, id string, at time.Time) error {
+	at = at.Truncate(time.Microsecond)
+	m.update(id, func(u *models.User) { u.LastLoginAt = &at })
+	return nil
+}
+
+func (m *memStore) UpdatePassword(ctx context.Context, id, passwordHash string) error {
+	m.update(id, func(u *models.User) {
+		u.PasswordHash = passwordHash
+		u.UpdatedAt = memNow()
+	})
+	return nil
+}
+
+func (m *memStore) UpdateEmail(ctx context.Context, id, email string) error {
+	defer m.lock()()
+	if err := m.checkEmail(id, email); err != nil {
+		return fmt.Errorf("store.UpdateEmail: %w", err)
+	}
+	u, ok := m.data.users[id]
+	if !ok {
+		return nil
+	}
+	u.Email = email
+	u.EmailVerified = true
+	u.UpdatedAt = memNow()
+	m.data.users[id] = u
+	return nil
+}
+
+func (m *memStore) SoftDelete(ctx context.Context, id string, at time.Time) error {
+	at = at.Truncate(time.Microsecond)
+	m.update(id, func(u *models.User) {
+		if u.DeletedAt == nil {
+			u.DeletedAt = &at
+			u.UpdatedAt = memNow()
+		}
+	})
+	return nil
+}
+
+func (m *memStore) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	defer m.lock()()
+	var n int64
+	for id, u := range m.data.users {
+		if u.DeletedAt != nil && u.DeletedAt.Before(before) {
+			delete(m.data.users, id)
+			n++
+		}
+	}
+	// Audit entries go with their user, like with ON DELETE CASCADE.
+	m.data.audit = slices.DeleteFunc(m.data.audit, func(e models.AuditEntry) bool {
+		_, ok := m.data.users[e.UserId]
+		return !ok
+	})
+	return n, nil
+}
+
+func (m *memStore) SetDisabled(ctx context.Context, id string, at *time.Time) error {
+	if at != nil {
+		t := at.Truncate(time.Microsecond)
+		at = &t
+	}
+	m.update(id, func(u *models.User) {
+		u.DisabledAt = at
+		u.UpdatedAt = memNow()
+	})
+	return nil
+}
+
+func (m *memStore) ResetTwoFactor(ctx context.Context, id string) error {
+	m.update(id, func(u *models.User) {
+		u.TwoFactorEnabled = false
+		u.UpdatedAt = memNow()
+	})
+	return nil
+}
+
+func (m *memStore) UpdateRoles(ctx context.Context, id string, roles []string) error {
+	roles = slices.Clone(roles)
+	m.update(id, func(u *models.User) {
+		u.Roles = roles
+		u.UpdatedAt = memNow()
+	})
+	return nil
+}
+
+func (m *memStore) ListUsers(ctx context.Context, q models.ListQuery) ([]models.User, error) {
+	key, ok := memUserSorts[q.Sort]
+	if !ok {
+		return nil, fmt.Errorf("store.ListUsers: cannot sort users on %q", q.Sort)
+	}
+	var after any
+	if q.After != nil {
+		v, err := key.cursor(q.After.Value)
+		if err != nil {
+			return nil, fmt.Errorf("store.ListUsers: invalid cursor on %q: %w", q.Sort, err)
+		}
+		after = v
+	}
+	compareUsers := func(a, b *models.User) int {
+		return cmp.Or(key.compare(key.value(a), key.value(b)), strings.Compare(a.Id, b.Id))
+	}
+
+	defer m.rlock()()
+	var users []models.User
+	for id := range m.data.users {
+		u, _ := m.get(id)
+		match, err := memMatchUser(u, q.Filters)
+		if err != nil {
+			return nil, fmt.Errorf("store.ListUsers: %w", err)
+		}
+		if !match {
+			continue
+		}
+		if q.After != nil {
+			c := cmp.Or(key.compare(key.value(u), after), strings.Compare(u.Id, q.After.Id))
+			if q.Desc {
+				c = -c
+			}
+			if c <= 0 {
+				continue
+			}
+		}
+		users = append(users, *u)
+	}
+	slices.SortFunc(users, func(a, b models.User) int {
+		if q.Desc {
+			return compareUsers(&b, &a)
+		}
+		return compareUsers(&a, &b)
+	})
+	if len(users) > q.Limit {
+		users = users[:q.Limit]
+	}
+	return users, nil
+}
+
+// memSort is a sortable field of the memory store.
+type memSort struct {
+	value   func(u *models.User) any
+	cursor  func(string) (any, error)
+	compare func(a, b any) int
+}
+
+var memUserSorts = map[string]memSort{
+	models.UserFieldCreatedAt: {
+		value: func(u *models.User) any { return u.CreatedAt },
+		cursor: func(v string) (any, error) {
+			return time.Parse(time.RFC3339Nano, v)
+		},
+		compare: func(a, b any) int { return a.(time.Time).Compare(b.(time.Time)) },
+	},
+	models.UserFieldEmail: {
+		value:   func(u *models.User) any { return u.Email },
+		cursor:  func(v string) (any, error) { return v, nil },
+		compare: func(a, b any) int { return strings.Compare(a.(string), b.(string)) },
+	},
+}
+
+// memMatchUser applies the filters the way userResource turns them into SQL.
+func memMatchUser(u *models.User, filters []models.Filter) (bool, error) {
+	for _, f := range filters {
+		var match bool
+		switch {
+		case f.Field == models.UserFieldEmail && f.Op == models.OpEq:
+			match = strings.EqualFold(u.Email, fmt.Sprint(f.Value))
+		case f.Field == models.UserFieldEmail && f.Op == models.OpPrefix:
+			match = strings.HasPrefix(strings.ToLower(u.Email), strings.ToLower(fmt.Sprint(f.Value)))
+		case f.Field == models.UserFieldRole && f.Op == models.OpEq:
+			match = slices.Contains(u.Roles, fmt.Sprint(f.Value))
+		case f.Field == models.UserFieldStatus && f.Op == models.OpEq:
+			match = u.Status() == f.Value
+		case f.Field == models.UserFieldCreatedAt && (f.Op == models.OpGte || f.Op == models.OpLt):
+			t, ok := f.Value.(time.Time)
+			if !ok {
+				return false, fmt.Errorf("cannot filter users on %q with %T", f.Field, f.Value)
+			}
+			match = !u.CreatedAt.Before(t)
+			if f.Op == models.OpLt {
+				match = u.CreatedAt.Before(t)
+			}
+		default:
+			return false, fmt.Errorf("cannot filter users on %q with %q", f.Field, f.Op)
+		}
+		if !match {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (m *memStore) AddAudit(ctx context.Context, entry *models.AuditEntry) error {
+	defer m.lock()()
+	if _, ok := m.data.users[entry.UserId]; !ok {
+		return fmt.Errorf("store.AddAudit: %w", &ports.ConstraintError{Kind: ports.ErrForeignKeyViolation, Constraint: "audit_entries_user_id_fkey"})
+	}
+	for _, e := range m.data.audit {
+		if e.Id == entry.Id {
+			return fmt.Errorf("store.AddAudit: %w", &ports.ConstraintError{Kind: ports.ErrUniqueViolation, Constraint: "audit_entries_pkey"})
+		}
+	}
+	e := *entry
+	e.CreatedAt = e.CreatedAt.Truncate(time.Microsecond)
+	m.data.audit = append(m.data.audit, e)
+	return nil
+}
+
+func (m *memStore) ListAudit(ctx context.Context, userId string) ([]models.AuditEntry, error) {
+	defer m.rlock()()
+	var entries []models.AuditEntry
+	for _, e := range m.data.audit {
+		if e.UserId == userId {
+			entries = append(entries, e)
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b models.AuditEntry) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return entries, nil
+}
+
+func (m *memStore) AddEvent(ctx context.Context, event *models.Event) error {
+	defer m.lock()()
+	for _, e := range m.data.events {
+		if e.Id == event.Id {
+			return fmt.Errorf("store.AddEvent: %w", &ports.ConstraintError{Kind: ports.ErrUniqueViolation, Constraint: "outbox_events_id_key"})
+		}
+	}
+	m.data.seq++
+	e := memEvent{Event: *event, availableAt: event.OccurredAt}
+	e.Seq = m.data.seq
+	e.Attempts = 0
+	m.data.events = append(m.data.events, e)
+	return nil
+}
+
+// ClaimEvents works like claimEventsQuery. Events are kept in order of Seq.
+func (m *memStore) ClaimEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Event, error) {
+	defer m.lock()()
+	blocked := map[string]bool{}
+	for _, e := range m.data.events {
+		if e.publishedAt == nil && e.availableAt.After(now) {
+			blocked[e.AggregateId] = true
+		}
+	}
+	var events []models.Event
+	for i := range m.data.events {
+		e := &m.data.events[i]
+		if len(events) == limit {
+			break
+		}
+		if e.publishedAt != nil || blocked[e.AggregateId] {
+			continue
+		}
+		e.availableAt = now.Add(lease)
+		events = append(events, e.Event)
+	}
+	return events, nil
+}
+
+// event applies change to the event if it exists.
+func (m *memStore) event(id string, change func(e *memEvent)) {
+	defer m.lock()()
+	for i := range m.data.events {
+		if m.data.events[i].Id == id {
+			change(&m.data.events[i])
+			return
+		}
+	}
+}
+
+func (m *memStore) MarkEventPublished(ctx context.Context, id string, at time.Time) error {
+	m.event(id, func(e *memEvent) { e.publishedAt = &at })
+	return nil
+}
+
+func (m *memStore) MarkEventFailed(ctx context.Context, id string, retryAt time.Time, reason string) error {
+	m.event(id, func(e *memEvent) {
+		e.Attempts++
+		e.availableAt = retryAt
+		e.lastError = reason
+	})
+	return nil
+}
+
+func (m *memStore) DeferEvent(ctx context.Context, id string, availableAt time.Time) error {
+	m.event(id, func(e *memEvent) { e.availableAt = availableAt })
+	return nil
+}
+
+func (m *memStore) PurgePublishedEvents(ctx context.Context, before time.Time) (int64, error) {
+	defer m.lock()()
+	n := len(m.data.events)
+	m.data.events = slices.DeleteFunc(m.data.events, func(e memEvent) bool {
+		return e.publishedAt != nil && e.publishedAt.Before(before)
+	})
+	return int64(n - len(m.data.events)), nil
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	queryDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "go-web",
+			Subsystem: "store",
+			Name:      "query_duration_seconds",
+			Help:      "Duration of the database queries, labeled by store method and database.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		},
+		[]string{"query", "db"},
+	)
+	queryErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "go-web",
+			Subsystem: "store",
+			Name:      "query_errors_total",
+			Help:      "Total number of failed database queries, labeled by store method and database.",
+		},
+		[]string{"query", "db"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(queryDuration, queryErrors)
+}
+
+// timedQuerier records the duration and the failures of the statements run
+// for the store method name, and logs those slower than slow, unless it is 0.
+type timedQuerier struct {
+	q    querier
+	name string
+	db   string
+	slow time.Duration
+}
+
+func (t timedQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	res, err := t.q.ExecContext(ctx, query, args...)
+	t.observe(start, query, args, err)
+	return res, err
+}
+
+// QueryContext only times the query until the first rows are received, not
+// while they are read.
+func (t timedQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := t.q.QueryContext(ctx, query, args...)
+	t.observe(start, query, args, err)
+	return rows, err
+}
+
+func (t timedQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	start := time.Now()
+	row := t.q.QueryRowContext(ctx, query, args...)
+	t.observe(start, query, args, row.Err())
+	return row
+}
+
+func (t timedQuerier) observe(start time.Time, query string, args []any, err error) {
+	elapsed := time.Since(start)
+	queryDuration.WithLabelValues(t.name, t.db).Observe(elapsed.Seconds())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		queryErrors.WithLabelValues(t.name, t.db).Inc()
+	}
+	if t.slow > 0 && elapsed >= t.slow {
+		slog.Warn("slow query",
+			"query", t.name,
+			"db", t.db,
+			"duration", elapsed,
+			"sql", strings.Join(strings.Fields(query), " "),
+			"args", redactArgs(args),
+		)
+	}
+}
+
+// redactArgs describes the arguments of a query by their type only, since
+// they hold emails, password hashes and the like.
+func redactArgs(args []any) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == nil {
+			redacted[i] = "NULL"
+		} else {
+			redacted[i] = fmt.Sprintf("%T", arg)
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
+)
+
+// outboxLockKey is the advisory lock that relays take in turn to claim
+// events, so that two never claim the events of the same aggregate.
+const outboxLockKey = 7_001
+
+// claimEventsQuery leases the oldest unpublished events of the aggregates
+// whose unpublished events are all available, i.e. neither leased nor waiting
+// to be retried. The events are returned in any order.
+const claimEventsQuery = `
+	UPDATE outbox_events
+	SET available_at = $2
+	WHERE seq IN (
+		SELECT seq
+		FROM outbox_events
+		WHERE published_at IS NULL AND aggregate_id IN (
+			SELECT aggregate_id
+			FROM outbox_events
+			WHERE published_at IS NULL
+			GROUP BY aggregate_id
+			HAVING max(available_at) <= $1
+		)
+		ORDER BY seq
+		LIMIT $3
+	)
+	RETURNING seq, id, type, aggregate_id, payload, occurred_at, attempts;
+`
+
+func (p *pgStore) AddEvent(ctx context.Context, event *models.Event) error {
+	query := `
+		INSERT INTO outbox_events (id, type, aggregate_id, payload, occurred_at, available_at)
+		VALUES ($1, $2, $3, $4, $5, $5);
+	`
+	_, err := p.writer("AddEvent").ExecContext(ctx, query, event.Id, event.Type, event.AggregateId, string(event.Payload), event.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("store.AddEvent: %w", translateError(err))
+	}
+	return nil
+}
+
+func (p *pgStore) ClaimEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Event, error) {
+	var events []models.Event
+	err := p.WithTx(ctx, func(tx ports.Store) error {
+		q := tx.(*pgStore).writer("ClaimEvents")
+		if _, err := q.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1);`, outboxLockKey); err != nil {
+			return err
+		}
+		rows, err := q.QueryContext(ctx, claimEventsQuery, now, now.Add(lease), limit)
+		if err != nil {
+			return err
+		}
+		events, err = scanEvents(rows, func(e *models.Event) any { return &e.OccurredAt })
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store.ClaimEvents: %w", err)
+	}
+	return events, nil
+}
+
+// scanEvents reads the rows of claimEventsQuery and sorts the events.
+// occurredAt gives where to scan the time of an event, which the drivers
+// return differently.
+func scanEvents(rows *sql.Rows, occurredAt func(e *models.Event) any) ([]models.Event, error) {
+	//nolint:errcheck
+	defer rows.Close()
+	var events []models.Event
+	for rows.Next() {
+		var e models.Event
+		var payload string
+		if err := rows.Scan(&e.Seq, &e.Id, &e.Type, &e.AggregateId, &payload, occurredAt(&e), &e.Attempts); err != nil {
+			return nil, err
+		}
+		e.Payload = []byte(payload)
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(events, func(a, b models.Event) int {
+		return cmp.Compare(a.Seq, b.Seq)
+	})
+	return events, nil
+}
+
+func (p *pgStore) MarkEventPublished(ctx context.Context, id string, at time.Time) error {
+	query := `UPDATE outbox_events SET published_at = $2 WHERE id = $1;`
+	if _, err := p.writer("MarkEventPublished").ExecContext(ctx, query, id, at); err != nil {
+		return fmt.Errorf("store.MarkEventPublished: %w", err)
+	}
+	return nil
+}
+
+func (p *pgStore) MarkEventFailed(ctx context.Context, id string, retryAt time.Time, reason string) error {
+	query := `
+		UPDATE outbox_events
+		SET attempts = attempts + 1, available_at = $2, last_error = $3
+		WHERE id = $1;
+	`
+	if _, err := p.writer("MarkEventFailed").ExecContext(ctx, query, id, retryAt, reason); err != nil {
+		return fmt.Errorf("store.MarkEventFailed: %w", err)
+	}
+	return nil
+}
+
+func (p *pgStore) DeferEvent(ctx context.Context, id string, availableAt time.Time) error {
+	query := `UPDATE outbox_events SET available_at = $2 WHERE id = $1;`
+	if _, err := p.writer("DeferEvent").ExecContext(ctx, query, id, availableAt); err != nil {
+		return fmt.Errorf("store.DeferEvent: %w", err)
+	}
+	return nil
+}
+
+func (p *pgStore) PurgePublishedEvents(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM outbox_events WHERE published_at < $1;`
+	res, err := p.writer("PurgePublishedEvents").ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("store.PurgePublishedEvents: %w", err)
+	}
+	return res.RowsAffected()
+}
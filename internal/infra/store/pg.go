@@ -1,30 +1,178 @@
 package store
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
 	"log/slog"
+	"time"
 
 	"go-web/internal/core/ports"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
+// querier is what the store methods need from *sql.DB and *sql.Tx, so that
+// they run the same inside and outside of a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type pgStore struct {
-	db *sql.DB
+	db  *sql.DB
+	q   querier
+	txs TxOptions
+	// replicas is nil without replicas.
+	replicas  *replicaSet
+	slowQuery time.Duration
+	// inTx is set on the store handed to the function of WithTx.
+	inTx bool
 }
 
-func NewPgStore(addr string) ports.Store {
-	db, err := sql.Open("postgres", addr)
-	if err != nil {
-		slog.Error("failed open db connection", "error=", err.Error())
-		return nil
-	}
-	if err := db.Ping(); err != nil {
-		slog.Error("failed to ping db", "error=", err.Error())
-		return nil
+// PgOptions configures the connection pool and transactions of the store.
+type PgOptions struct {
+	// MaxOpenConns of 0 leaves the pool unbounded.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// ConnectTimeout is how long the first connection is retried. It is tried
+	// once when 0.
+	ConnectTimeout time.Duration
+	Tx             TxOptions
+	// Replicas get the same pool settings as the primary and are checked
+	// every ReplicaCheckInterval.
+	Replicas             []PgReplica
+	ReplicaCheckInterval time.Duration
+	// SlowQueryThreshold logs the queries taking longer, when not 0.
+	SlowQueryThreshold time.Duration
+	// Metrics registers the statistics of the connection pools when set.
+	Metrics prometheus.Registerer
+}
+
+var DefaultPgOptions = PgOptions{
+	MaxOpenConns:    100,
+	MaxIdleConns:    50,
+	ConnMaxLifetime: 30 * time.Minute,
+	ConnMaxIdleTime: 5 * time.Minute,
+	ConnectTimeout:  5 * time.Second,
+	Tx:              DefaultTxOptions,
+
+	ReplicaCheckInterval: 5 * time.Second,
+}
+
+// maxConnectBackoff caps the wait between two connection attempts.
+const maxConnectBackoff = 5 * time.Second
+
+func NewPgStore(ctx context.Context, addr string) (ports.Store, error) {
+	return NewPgStoreWithDSN(ctx, func() string { return addr }, DefaultPgOptions)
+}
+
+// NewPgStoreWithDSN resolves the DSN every time a new connection is opened, so
+// a rotated database password only affects connections created afterwards.
+// It fails when the database cannot be reached within opts.ConnectTimeout.
+// Replicas down at startup are not an error, reads go elsewhere until they
+// answer a health check. They are checked until ctx is done.
+func NewPgStoreWithDSN(ctx context.Context, dsn func() string, opts PgOptions) (ports.Store, error) {
+	db := openPg(dsn, opts)
+	if err := ping(ctx, db, opts.ConnectTimeout); err != nil {
+		//nolint:errcheck
+		db.Close()
+		return nil, err
 	}
-	db.SetMaxOpenConns(100)
-	db.SetMaxIdleConns(50)
 	slog.Info("db connected")
-	return &pgStore{db: db}
+	s := &pgStore{db: db, q: db, txs: opts.Tx, slowQuery: opts.SlowQueryThreshold}
+	pools := map[string]*sql.DB{"primary": db}
+	if len(opts.Replicas) > 0 {
+		s.replicas = &replicaSet{}
+		for _, r := range opts.Replicas {
+			rep := &replica{name: r.Name, db: openPg(r.DSN, opts)}
+			// Assumed healthy, so that the first check logs those that are not.
+			rep.healthy.Store(true)
+			s.replicas.replicas = append(s.replicas.replicas, rep)
+			pools[r.Name] = rep.db
+		}
+		s.replicas.check(ctx, opts.ReplicaCheckInterval)
+		go s.replicas.run(ctx, opts.ReplicaCheckInterval)
+	}
+	if opts.Metrics != nil {
+		for name, pool := range pools {
+			if err := opts.Metrics.Register(collectors.NewDBStatsCollector(pool, name)); err != nil {
+				return nil, fmt.Errorf("store: cannot register the metrics of %s: %w", name, err)
+			}
+		}
+	}
+	return s, nil
+}
+
+func openPg(dsn func() string, opts PgOptions) *sql.DB {
+	db := sql.OpenDB(&dsnConnector{dsn: dsn})
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	return db
+}
+
+// writer returns where the statements of the store method name run: the
+// primary, or the transaction.
+func (p *pgStore) writer(name string) querier {
+	return timedQuerier{q: p.q, name: name, db: "primary", slow: p.slowQuery}
+}
+
+// reader is writer for reads, which go to a healthy replica unless they are
+// part of a transaction, ctx asks for the primary or no replica is healthy.
+func (p *pgStore) reader(ctx context.Context, name string) querier {
+	if p.inTx || p.replicas == nil || ports.UsePrimary(ctx) {
+		return p.writer(name)
+	}
+	if rep := p.replicas.pick(); rep != nil {
+		return timedQuerier{q: rep.db, name: name, db: rep.name, slow: p.slowQuery}
+	}
+	return p.writer(name)
+}
+
+// ping waits for the database to answer, retrying with an exponential
+// back-off until timeout, e.g. while it starts next to the server.
+func ping(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	backoff := 100 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("store: cannot connect to postgres after %d attempts: %w", attempt, err)
+		}
+		slog.Warn("failed to connect to postgres, retrying", "attempt", attempt, "backoff", backoff, "error", err.Error())
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("store: cannot connect to postgres: %w", errors.Join(ctx.Err(), err))
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxConnectBackoff)
+	}
+}
+
+type dsnConnector struct {
+	dsn func() string
+}
+
+func (c *dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	connector, err := pq.NewConnector(c.dsn())
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return &pq.Driver{}
 }
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go-web/internal/core/models"
+)
+
+// queryField maps a field of models.ListQuery to SQL.
+type queryField struct {
+	// column is the SQL column of the field. Only fields with a column can be
+	// sorted on.
+	column string
+	// cast is the type a cursor value is cast to when sorting on the field.
+	cast string
+	// cursor converts a cursor value to the representation of the column,
+	// for databases that cannot cast it. The value is sent as is when nil.
+	cursor func(string) (any, error)
+	// filter returns the condition of a filter on the field. bind adds a value
+	// to the query arguments and returns its placeholder.
+	filter func(op models.FilterOp, value any, bind func(any) string) (string, error)
+}
+
+// queryResource describes how a resource is listed.
+type queryResource struct {
+	table   string
+	columns string
+	fields  map[string]queryField
+}
+
+// build turns q into a parameterized query paging through the resource with
+// keyset pagination on the sort column and id, so that a page costs the same
+// however deep it is. Only values are sent as arguments; field names must be
+// known to the resource.
+func (res queryResource) build(q models.ListQuery) (string, []any, error) {
+	var where []string
+	var args []any
+	bind := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	for _, f := range q.Filters {
+		field, ok := res.fields[f.Field]
+		if !ok || field.filter == nil {
+			return "", nil, fmt.Errorf("cannot filter %s on %q", res.table, f.Field)
+		}
+		cond, err := field.filter(f.Op, f.Value, bind)
+		if err != nil {
+			return "", nil, fmt.Errorf("cannot filter %s on %q: %w", res.table, f.Field, err)
+		}
+		where = append(where, "("+cond+")")
+	}
+	sort, ok := res.fields[q.Sort]
+	if !ok || sort.column == "" {
+		return "", nil, fmt.Errorf("cannot sort %s on %q", res.table, q.Sort)
+	}
+	order, cmp := "ASC", ">"
+	if q.Desc {
+		order, cmp = "DESC", "<"
+	}
+	if q.After != nil {
+		var value any = q.After.Value
+		if sort.cursor != nil {
+			v, err := sort.cursor(q.After.Value)
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid cursor on %q: %w", q.Sort, err)
+			}
+			value = v
+		}
+		placeholder := bind(value)
+		if sort.cast != "" {
+			placeholder += "::" + sort.cast
+		}
+		where = append(where, fmt.Sprintf("(%s, id) %s (%s, %s)",
+			sort.column, cmp, placeholder, bind(q.After.Id)))
+	}
+	query := "SELECT " + res.columns + " FROM " + res.table
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %s", sort.column, order, order, bind(q.Limit))
+	return query, args, nil
+}
+
+// compare filters column with the comparison operators.
+func compare(column string) func(models.FilterOp, any, func(any) string) (string, error) {
+	return func(op models.FilterOp, value any, bind func(any) string) (string, error) {
+		switch op {
+		case models.OpEq:
+			return column + " = " + bind(value), nil
+		case models.OpGte:
+			return column + " >= " + bind(value), nil
+		case models.OpLt:
+			return column + " < " + bind(value), nil
+		case models.OpPrefix:
+			s, ok := value.(string)
+			if !ok {
+				return "", fmt.Errorf("prefix must be a string")
+			}
+			return column + " LIKE " + bind(escapeLike(s)+"%") + ` ESCAPE '\'`, nil
+		}
+		return "", fmt.Errorf("unsupported operator %q", op)
+	}
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
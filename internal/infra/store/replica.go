@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// PgReplica is a read replica. Name identifies it in the logs and must not
+// contain the password.
+type PgReplica struct {
+	Name string
+	DSN  func() string
+}
+
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// replicaSet spreads reads round-robin over the replicas that answered their
+// last health check.
+type replicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+// pick returns the next healthy replica, or nil when none is.
+func (r *replicaSet) pick() *replica {
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := range n {
+		if rep := r.replicas[(start+i)%n]; rep.healthy.Load() {
+			return rep
+		}
+	}
+	return nil
+}
+
+// check pings every replica and records which ones answered.
+func (r *replicaSet) check(ctx context.Context, timeout time.Duration) {
+	for _, rep := range r.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := rep.db.PingContext(pingCtx)
+		cancel()
+		healthy := err == nil
+		if rep.healthy.Swap(healthy) == healthy {
+			continue
+		}
+		if healthy {
+			slog.Info("db replica is healthy, reading from it", "replica", rep.name)
+		} else {
+			slog.Warn("db replica is unhealthy, reading elsewhere", "replica", rep.name, "error", err.Error())
+		}
+	}
+}
+
+// run checks the replicas on every interval until ctx is done.
+func (r *replicaSet) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.check(ctx, interval)
+		}
+	}
+}
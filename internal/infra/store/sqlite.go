@@ -0,0 +1,193 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-web/internal/core/ports"
+
+	"modernc.org/sqlite"
+)
+
+// sqliteSchema mirrors the Postgres migrations. SQLite has no arrays, so
+// roles are a JSON array, and times are stored as fixed width UTC text, so
+// that comparing them as text compares them as times.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT NOT NULL PRIMARY KEY,
+    email TEXT NOT NULL,
+    password_hash TEXT NOT NULL,
+    display_name TEXT NOT NULL DEFAULT '',
+    avatar_url TEXT NOT NULL DEFAULT '',
+    locale TEXT NOT NULL DEFAULT '',
+    timezone TEXT NOT NULL DEFAULT '',
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    two_factor_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    roles TEXT NOT NULL DEFAULT '["user"]',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    last_login_at TIMESTAMP,
+    disabled_at TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (lower(email));
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS audit_entries (
+    id TEXT NOT NULL PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    action TEXT NOT NULL,
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_entries_user_id ON audit_entries (user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    id TEXT NOT NULL UNIQUE,
+    type TEXT NOT NULL,
+    aggregate_id TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    available_at TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    published_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events (aggregate_id, seq) WHERE published_at IS NULL;
+`
+
+type sqliteStore struct {
+	db   *sql.DB
+	q    querier
+	inTx bool
+}
+
+// NewSQLiteStore opens the database file at path, ":memory:" for a database
+// that lives as long as the store, and creates the schema if needed.
+func NewSQLiteStore(path string) (ports.Store, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("store: cannot open sqlite db %s: %w", path, err)
+	}
+	// SQLite allows a single writer, and an in memory database is private to
+	// its connection.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		//nolint:errcheck
+		db.Close()
+		return nil, fmt.Errorf("store: cannot create the sqlite schema in %s: %w", path, err)
+	}
+	slog.Info("sqlite db opened", "path", path)
+	return &sqliteStore{db: db, q: db}, nil
+}
+
+// SQLite transactions are serializable and, with a single connection, never
+// conflict, so they are not retried.
+func (s *sqliteStore) WithTx(ctx context.Context, fn func(tx ports.Store) error) (err error) {
+	if s.inTx {
+		return fn(s)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store.WithTx: %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			//nolint:errcheck
+			tx.Rollback()
+			panic(r)
+		}
+		if err != nil {
+			//nolint:errcheck
+			tx.Rollback()
+		}
+	}()
+	if err = fn(&sqliteStore{db: s.db, q: tx, inTx: true}); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("store.WithTx: %w", translateSQLiteError(err))
+	}
+	return nil
+}
+
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+// sqliteTime formats t the way times are stored.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+func sqliteNullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return sqliteTime(*t)
+}
+
+// sqliteTimeDest scans a time stored by sqliteTime, which the driver only
+// parses itself for the columns it knows to be times.
+type sqliteTimeDest struct {
+	dest *time.Time
+}
+
+func (d sqliteTimeDest) Scan(src any) error {
+	switch v := src.(type) {
+	case time.Time:
+		*d.dest = v
+		return nil
+	case string:
+		t, err := time.Parse(sqliteTimeLayout, v)
+		*d.dest = t
+		return err
+	}
+	return fmt.Errorf("cannot scan %T into a time", src)
+}
+
+// jsonStrings scans a JSON array of strings.
+type jsonStrings struct {
+	dest *[]string
+}
+
+func (j jsonStrings) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), j.dest)
+	case []byte:
+		return json.Unmarshal(v, j.dest)
+	}
+	return fmt.Errorf("cannot scan %T into a list of strings", src)
+}
+
+// sqliteConstraintKinds maps the extended result codes of constraint
+// violations to their kind.
+var sqliteConstraintKinds = map[int]error{
+	2067: ports.ErrUniqueViolation,     // SQLITE_CONSTRAINT_UNIQUE
+	1555: ports.ErrUniqueViolation,     // SQLITE_CONSTRAINT_PRIMARYKEY
+	787:  ports.ErrForeignKeyViolation, // SQLITE_CONSTRAINT_FOREIGNKEY
+	275:  ports.ErrCheckViolation,      // SQLITE_CONSTRAINT_CHECK
+}
+
+// translateSQLiteError is translateError for SQLite, which does not name the
+// violated constraint.
+func translateSQLiteError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	kind, ok := sqliteConstraintKinds[sqliteErr.Code()]
+	if !ok {
+		return err
+	}
+	return &ports.ConstraintError{Kind: kind, Err: err}
+}
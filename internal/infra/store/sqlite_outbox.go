@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
+)
+
+func (s *sqliteStore) AddEvent(ctx context.Context, event *models.Event) error {
+	query := `
+		INSERT INTO outbox_events (id, type, aggregate_id, payload, occurred_at, available_at)
+		VALUES ($1, $2, $3, $4, $5, $5);
+	`
+	return s.exec(ctx, "AddEvent", query, event.Id, event.Type, event.AggregateId, string(event.Payload), sqliteTime(event.OccurredAt))
+}
+
+// ClaimEvents needs no lock, SQLite runs one transaction at a time.
+func (s *sqliteStore) ClaimEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Event, error) {
+	var events []models.Event
+	err := s.WithTx(ctx, func(tx ports.Store) error {
+		rows, err := tx.(*sqliteStore).q.QueryContext(ctx, claimEventsQuery, sqliteTime(now), sqliteTime(now.Add(lease)), limit)
+		if err != nil {
+			return err
+		}
+		events, err = scanEvents(rows, func(e *models.Event) any { return sqliteTimeDest{&e.OccurredAt} })
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store.ClaimEvents: %w", err)
+	}
+	return events, nil
+}
+
+func (s *sqliteStore) MarkEventPublished(ctx context.Context, id string, at time.Time) error {
+	query := `UPDATE outbox_events SET published_at = $2 WHERE id = $1;`
+	return s.exec(ctx, "MarkEventPublished", query, id, sqliteTime(at))
+}
+
+func (s *sqliteStore) MarkEventFailed(ctx context.Context, id string, retryAt time.Time, reason string) error {
+	query := `
+		UPDATE outbox_events
+		SET attempts = attempts + 1, available_at = $2, last_error = $3
+		WHERE id = $1;
+	`
+	return s.exec(ctx, "MarkEventFailed", query, id, sqliteTime(retryAt), reason)
+}
+
+func (s *sqliteStore) DeferEvent(ctx context.Context, id string, availableAt time.Time) error {
+	query := `UPDATE outbox_events SET available_at = $2 WHERE id = $1;`
+	return s.exec(ctx, "DeferEvent", query, id, sqliteTime(availableAt))
+}
+
+func (s *sqliteStore) PurgePublishedEvents(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM outbox_events WHERE published_at < $1;`
+	res, err := s.q.ExecContext(ctx, query, sqliteTime(before))
+	if err != nil {
+		return 0, fmt.Errorf("store.PurgePublishedEvents: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,190 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go-web/internal/core/models"
+)
+
+func scanSQLiteUser(row rowScanner) (*models.User, error) {
+	var u models.User
+	if err := row.Scan(userFields(&u, jsonStrings{&u.Roles})...); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (s *sqliteStore) Create(ctx context.Context, user *models.User) (*models.User, error) {
+	query := `
+		INSERT INTO users (id, email, password_hash, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		RETURNING ` + userColumns + `;
+	`
+	row := s.q.QueryRowContext(ctx, query, user.Id, user.Email, user.PasswordHash, sqliteTime(time.Now()))
+	u, err := scanSQLiteUser(row)
+	if err != nil {
+		return nil, fmt.Errorf("store.Create: %w", translateSQLiteError(err))
+	}
+	return u, nil
+}
+
+func (s *sqliteStore) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE lower(email) = lower($1);
+	`
+	return s.findUser(ctx, "FindByEmail", query, email)
+}
+
+func (s *sqliteStore) FindByID(ctx context.Context, id string) (*models.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = $1;
+	`
+	return s.findUser(ctx, "FindByID", query, id)
+}
+
+// findUser returns the user selected by query, or nil when there is none.
+func (s *sqliteStore) findUser(ctx context.Context, op, query string, args ...any) (*models.User, error) {
+	u, err := scanSQLiteUser(s.q.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("store.%s: %w", op, translateSQLiteError(err))
+	}
+	return u, nil
+}
+
+func (s *sqliteStore) Update(ctx context.Context, user *models.User) (*models.User, error) {
+	query := `
+		UPDATE users
+		SET display_name = $2, avatar_url = $3, locale = $4, timezone = $5, updated_at = $7
+		WHERE id = $1 AND updated_at = $6
+		RETURNING ` + userColumns + `;
+	`
+	return s.findUser(ctx, "Update", query,
+		user.Id, user.DisplayName, user.AvatarURL, user.Locale, user.Timezone, sqliteTime(user.UpdatedAt), sqliteTime(time.Now()))
+}
+
+// exec runs a statement that returns no rows.
+func (s *sqliteStore) exec(ctx context.Context, op, query string, args ...any) error {
+	if _, err := s.q.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("store.%s: %w", op, translateSQLiteError(err))
+	}
+	return nil
+}
+
+// UpdateLastLogin leaves updated_at untouched since a login is not a change to
+// the profile.
+func (s *sqliteStore) UpdateLastLogin(ctx context.Context, id string, at time.Time) error {
+	return s.exec(ctx, "UpdateLastLogin", `UPDATE users SET last_login_at = $2 WHERE id = $1;`, id, sqliteTime(at))
+}
+
+func (s *sqliteStore) UpdatePassword(ctx context.Context, id, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $2, updated_at = $3 WHERE id = $1;`
+	return s.exec(ctx, "UpdatePassword", query, id, passwordHash, sqliteTime(time.Now()))
+}
+
+func (s *sqliteStore) UpdateEmail(ctx context.Context, id, email string) error {
+	query := `UPDATE users SET email = $2, email_verified = TRUE, updated_at = $3 WHERE id = $1;`
+	return s.exec(ctx, "UpdateEmail", query, id, email, sqliteTime(time.Now()))
+}
+
+func (s *sqliteStore) SoftDelete(ctx context.Context, id string, at time.Time) error {
+	query := `UPDATE users SET deleted_at = $2, updated_at = $3 WHERE id = $1 AND deleted_at IS NULL;`
+	return s.exec(ctx, "SoftDelete", query, id, sqliteTime(at), sqliteTime(time.Now()))
+}
+
+func (s *sqliteStore) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < $1;`
+	res, err := s.q.ExecContext(ctx, query, sqliteTime(before))
+	if err != nil {
+		return 0, fmt.Errorf("store.PurgeDeleted: %w", translateSQLiteError(err))
+	}
+	return res.RowsAffected()
+}
+
+func (s *sqliteStore) SetDisabled(ctx context.Context, id string, at *time.Time) error {
+	query := `UPDATE users SET disabled_at = $2, updated_at = $3 WHERE id = $1;`
+	return s.exec(ctx, "SetDisabled", query, id, sqliteNullTime(at), sqliteTime(time.Now()))
+}
+
+func (s *sqliteStore) ResetTwoFactor(ctx context.Context, id string) error {
+	query := `UPDATE users SET two_factor_enabled = FALSE, updated_at = $2 WHERE id = $1;`
+	return s.exec(ctx, "ResetTwoFactor", query, id, sqliteTime(time.Now()))
+}
+
+func (s *sqliteStore) UpdateRoles(ctx context.Context, id string, roles []string) error {
+	if roles == nil {
+		roles = []string{}
+	}
+	b, err := json.Marshal(roles)
+	if err != nil {
+		return fmt.Errorf("store.UpdateRoles: %w", err)
+	}
+	query := `UPDATE users SET roles = $2, updated_at = $3 WHERE id = $1;`
+	return s.exec(ctx, "UpdateRoles", query, id, string(b), sqliteTime(time.Now()))
+}
+
+var sqliteUserResource = queryResource{
+	table:   "users",
+	columns: userColumns,
+	fields: map[string]queryField{
+		models.UserFieldEmail: {column: "email", filter: filterUserEmail},
+		models.UserFieldCreatedAt: {
+			column: "created_at",
+			cursor: func(v string) (any, error) {
+				t, err := time.Parse(time.RFC3339Nano, v)
+				if err != nil {
+					return nil, err
+				}
+				return sqliteTime(t), nil
+			},
+			filter: func(op models.FilterOp, value any, bind func(any) string) (string, error) {
+				if t, ok := value.(time.Time); ok {
+					value = sqliteTime(t)
+				}
+				return compare("created_at")(op, value, bind)
+			},
+		},
+		models.UserFieldRole: {filter: func(op models.FilterOp, value any, bind func(any) string) (string, error) {
+			if op != models.OpEq {
+				return "", fmt.Errorf("unsupported operator %q", op)
+			}
+			return "EXISTS (SELECT 1 FROM json_each(roles) WHERE value = " + bind(value) + ")", nil
+		}},
+		models.UserFieldStatus: {filter: filterUserStatus},
+	},
+}
+
+func (s *sqliteStore) ListUsers(ctx context.Context, q models.ListQuery) ([]models.User, error) {
+	query, args, err := sqliteUserResource.build(q)
+	if err != nil {
+		return nil, fmt.Errorf("store.ListUsers: %w", err)
+	}
+	rows, err := s.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("store.ListUsers: %w", err)
+	}
+	//nolint:errcheck
+	defer rows.Close()
+	var users []models.User
+	for rows.Next() {
+		u, err := scanSQLiteUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store.ListUsers: %w", err)
+		}
+		users = append(users, *u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store.ListUsers: %w", err)
+	}
+	return users, nil
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"go-web/internal/core/ports"
+
+	"github.com/lib/pq"
+)
+
+// TxOptions configures the transactions run by WithTx.
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	// MaxRetries is how many times a transaction that failed on a
+	// serialization failure is run again.
+	MaxRetries int
+}
+
+var DefaultTxOptions = TxOptions{Isolation: sql.LevelDefault, MaxRetries: 3}
+
+// isolationLevels maps the isolation names of the configuration to levels.
+var isolationLevels = map[string]sql.IsolationLevel{
+	"":                sql.LevelDefault,
+	"read_committed":  sql.LevelReadCommitted,
+	"repeatable_read": sql.LevelRepeatableRead,
+	"serializable":    sql.LevelSerializable,
+}
+
+// IsolationLevel returns the level named name. An empty name is the default
+// level of the database.
+func IsolationLevel(name string) (sql.IsolationLevel, error) {
+	level, ok := isolationLevels[name]
+	if !ok {
+		return sql.LevelDefault, fmt.Errorf("unknown isolation level %q", name)
+	}
+	return level, nil
+}
+
+// serializationFailure is the SQLSTATE of a transaction that Postgres aborted
+// because it could not be serialized with concurrent ones.
+const serializationFailure = "40001"
+
+func (p *pgStore) WithTx(ctx context.Context, fn func(tx ports.Store) error) error {
+	if p.inTx {
+		return fn(p)
+	}
+	for attempt := 0; ; attempt++ {
+		err := p.runTx(ctx, fn)
+		if err == nil || !isSerializationFailure(err) || attempt >= p.txs.MaxRetries {
+			return err
+		}
+		slog.Debug("retrying transaction after a serialization failure", "attempt", attempt+1)
+		backoff := time.Duration(1<<attempt)*10*time.Millisecond + rand.N(10*time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (p *pgStore) runTx(ctx context.Context, fn func(tx ports.Store) error) (err error) {
+	tx, err := p.db.BeginTx(ctx, &sql.TxOptions{Isolation: p.txs.Isolation})
+	if err != nil {
+		return fmt.Errorf("store.WithTx: %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			//nolint:errcheck
+			tx.Rollback()
+			panic(r)
+		}
+		if err != nil {
+			//nolint:errcheck
+			tx.Rollback()
+		}
+	}()
+	if err = fn(&pgStore{db: p.db, q: tx, txs: p.txs, slowQuery: p.slowQuery, inTx: true}); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("store.WithTx: %w", translateError(err))
+	}
+	return nil
+}
+
+func isSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == serializationFailure
+}
@@ -4,37 +4,276 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	"go-web/internal/core/models"
+
+	"github.com/lib/pq"
 )
 
+const userColumns = `id, email, password_hash, display_name, avatar_url, locale, timezone,
+	email_verified, two_factor_enabled, roles, created_at, updated_at, last_login_at, disabled_at, deleted_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
+	var u models.User
+	if err := row.Scan(userFields(&u, pq.Array(&u.Roles))...); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// userFields returns the scan destinations of userColumns. Databases store
+// roles differently, so their destination is given.
+func userFields(u *models.User, roles any) []any {
+	return []any{
+		&u.Id,
+		&u.Email,
+		&u.PasswordHash,
+		&u.DisplayName,
+		&u.AvatarURL,
+		&u.Locale,
+		&u.Timezone,
+		&u.EmailVerified,
+		&u.TwoFactorEnabled,
+		roles,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+		&u.LastLoginAt,
+		&u.DisabledAt,
+		&u.DeletedAt,
+	}
+}
+
 func (p *pgStore) Create(ctx context.Context, user *models.User) (*models.User, error) {
 	query := `
 		INSERT INTO users (id, email, password_hash)
 		VALUES ($1, $2, $3)
-		RETURNING id, email, password_hash;
+		RETURNING ` + userColumns + `;
 	`
-	row := p.db.QueryRowContext(ctx, query, user.Id, user.Email, user.PasswordHash)
-	var u models.User
-	if err := row.Scan(&u.Id, &u.Email, &u.PasswordHash); err != nil {
-		return nil, fmt.Errorf("store.Create: %w", err)
+	row := p.writer("Create").QueryRowContext(ctx, query, user.Id, user.Email, user.PasswordHash)
+	u, err := scanUser(row)
+	if err != nil {
+		return nil, fmt.Errorf("store.Create: %w", translateError(err))
 	}
-	return &u, nil
+	return u, nil
 }
 
 func (p *pgStore) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, email, password_hash
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE email = $1;
+		WHERE lower(email) = lower($1);
 	`
-	row := p.db.QueryRowContext(ctx, query, email)
-	var u models.User
-	if err := row.Scan(&u.Id, &u.Email, &u.PasswordHash); err != nil {
+	row := p.reader(ctx, "FindByEmail").QueryRowContext(ctx, query, email)
+	u, err := scanUser(row)
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("store.FindByEmail: %w", err)
 	}
-	return &u, nil
+	return u, nil
+}
+
+func (p *pgStore) FindByID(ctx context.Context, id string) (*models.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = $1;
+	`
+	row := p.reader(ctx, "FindByID").QueryRowContext(ctx, query, id)
+	u, err := scanUser(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("store.FindByID: %w", err)
+	}
+	return u, nil
+}
+
+func (p *pgStore) Update(ctx context.Context, user *models.User) (*models.User, error) {
+	query := `
+		UPDATE users
+		SET display_name = $2, avatar_url = $3, locale = $4, timezone = $5, updated_at = NOW()
+		WHERE id = $1 AND updated_at = $6
+		RETURNING ` + userColumns + `;
+	`
+	row := p.writer("Update").QueryRowContext(ctx, query,
+		user.Id, user.DisplayName, user.AvatarURL, user.Locale, user.Timezone, user.UpdatedAt)
+	u, err := scanUser(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("store.Update: %w", translateError(err))
+	}
+	return u, nil
+}
+
+// UpdateLastLogin leaves updated_at untouched since a login is not a change to
+// the profile.
+func (p *pgStore) UpdateLastLogin(ctx context.Context, id string, at time.Time) error {
+	query := `
+		UPDATE users
+		SET last_login_at = $2
+		WHERE id = $1;
+	`
+	if _, err := p.writer("UpdateLastLogin").ExecContext(ctx, query, id, at); err != nil {
+		return fmt.Errorf("store.UpdateLastLogin: %w", translateError(err))
+	}
+	return nil
+}
+
+func (p *pgStore) UpdatePassword(ctx context.Context, id, passwordHash string) error {
+	query := `
+		UPDATE users
+		SET password_hash = $2, updated_at = NOW()
+		WHERE id = $1;
+	`
+	if _, err := p.writer("UpdatePassword").ExecContext(ctx, query, id, passwordHash); err != nil {
+		return fmt.Errorf("store.UpdatePassword: %w", translateError(err))
+	}
+	return nil
+}
+
+func (p *pgStore) UpdateEmail(ctx context.Context, id, email string) error {
+	query := `
+		UPDATE users
+		SET email = $2, email_verified = TRUE, updated_at = NOW()
+		WHERE id = $1;
+	`
+	if _, err := p.writer("UpdateEmail").ExecContext(ctx, query, id, email); err != nil {
+		return fmt.Errorf("store.UpdateEmail: %w", translateError(err))
+	}
+	return nil
+}
+
+func (p *pgStore) SoftDelete(ctx context.Context, id string, at time.Time) error {
+	query := `
+		UPDATE users
+		SET deleted_at = $2, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL;
+	`
+	if _, err := p.writer("SoftDelete").ExecContext(ctx, query, id, at); err != nil {
+		return fmt.Errorf("store.SoftDelete: %w", translateError(err))
+	}
+	return nil
+}
+
+func (p *pgStore) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM users
+		WHERE deleted_at IS NOT NULL AND deleted_at < $1;
+	`
+	res, err := p.writer("PurgeDeleted").ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("store.PurgeDeleted: %w", translateError(err))
+	}
+	return res.RowsAffected()
+}
+
+func (p *pgStore) SetDisabled(ctx context.Context, id string, at *time.Time) error {
+	query := `
+		UPDATE users
+		SET disabled_at = $2, updated_at = NOW()
+		WHERE id = $1;
+	`
+	if _, err := p.writer("SetDisabled").ExecContext(ctx, query, id, at); err != nil {
+		return fmt.Errorf("store.SetDisabled: %w", translateError(err))
+	}
+	return nil
+}
+
+func (p *pgStore) ResetTwoFactor(ctx context.Context, id string) error {
+	query := `
+		UPDATE users
+		SET two_factor_enabled = FALSE, updated_at = NOW()
+		WHERE id = $1;
+	`
+	if _, err := p.writer("ResetTwoFactor").ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("store.ResetTwoFactor: %w", translateError(err))
+	}
+	return nil
+}
+
+func (p *pgStore) UpdateRoles(ctx context.Context, id string, roles []string) error {
+	query := `
+		UPDATE users
+		SET roles = $2, updated_at = NOW()
+		WHERE id = $1;
+	`
+	if _, err := p.writer("UpdateRoles").ExecContext(ctx, query, id, pq.Array(roles)); err != nil {
+		return fmt.Errorf("store.UpdateRoles: %w", translateError(err))
+	}
+	return nil
+}
+
+var userResource = queryResource{
+	table:   "users",
+	columns: userColumns,
+	fields: map[string]queryField{
+		models.UserFieldEmail:     {column: "email", cast: "text", filter: filterUserEmail},
+		models.UserFieldCreatedAt: {column: "created_at", cast: "timestamptz", filter: compare("created_at")},
+		models.UserFieldRole: {filter: func(op models.FilterOp, value any, bind func(any) string) (string, error) {
+			if op != models.OpEq {
+				return "", fmt.Errorf("unsupported operator %q", op)
+			}
+			return bind(value) + " = ANY(roles)", nil
+		}},
+		models.UserFieldStatus: {filter: filterUserStatus},
+	},
+}
+
+// filterUserEmail matches emails ignoring case, like FindByEmail.
+func filterUserEmail(op models.FilterOp, value any, bind func(any) string) (string, error) {
+	if s, ok := value.(string); ok {
+		value = strings.ToLower(s)
+	}
+	return compare("lower(email)")(op, value, bind)
+}
+
+func filterUserStatus(op models.FilterOp, value any, _ func(any) string) (string, error) {
+	if op != models.OpEq {
+		return "", fmt.Errorf("unsupported operator %q", op)
+	}
+	switch value {
+	case models.UserActive:
+		return "deleted_at IS NULL AND disabled_at IS NULL", nil
+	case models.UserDisabled:
+		return "deleted_at IS NULL AND disabled_at IS NOT NULL", nil
+	case models.UserDeleted:
+		return "deleted_at IS NOT NULL", nil
+	}
+	return "", fmt.Errorf("unknown status %v", value)
+}
+
+func (p *pgStore) ListUsers(ctx context.Context, q models.ListQuery) ([]models.User, error) {
+	query, args, err := userResource.build(q)
+	if err != nil {
+		return nil, fmt.Errorf("store.ListUsers: %w", err)
+	}
+	rows, err := p.reader(ctx, "ListUsers").QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("store.ListUsers: %w", err)
+	}
+	//nolint:errcheck
+	defer rows.Close()
+	var users []models.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store.ListUsers: %w", err)
+		}
+		users = append(users, *u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store.ListUsers: %w", err)
+	}
+	return users, nil
 }
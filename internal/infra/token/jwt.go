@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"sync"
 	"time"
 
 	"go-web/internal/core/ports"
@@ -12,12 +13,39 @@ import (
 )
 
 type jwtGenerator struct {
-	secret []byte
+	secret func() string
 	exp    time.Duration
+
+	mu        sync.Mutex
+	current   string
+	previous  string
+	rotatedAt time.Time
 }
 
 func NewJwtGenerator(secret string, exp time.Duration) ports.TokenGenerator {
-	return &jwtGenerator{secret: []byte(secret), exp: exp}
+	return NewRotatingJwtGenerator(func() string { return secret }, exp)
+}
+
+// NewRotatingJwtGenerator looks the signing secret up on every call so that a
+// rotated secret takes effect without a restart. Tokens are signed with the
+// current secret only, but those signed with the previous one stay valid for
+// one token lifetime after the rotation, so that it logs nobody out.
+func NewRotatingJwtGenerator(secret func() string, exp time.Duration) ports.TokenGenerator {
+	return &jwtGenerator{secret: secret, exp: exp, current: secret()}
+}
+
+// secrets returns the current secret, and the previous one while tokens it
+// signed may still be valid.
+func (j *jwtGenerator) secrets() (current, previous string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if s := j.secret(); s != j.current {
+		j.previous, j.current, j.rotatedAt = j.current, s, time.Now()
+	}
+	if j.previous != "" && time.Since(j.rotatedAt) < j.exp {
+		return j.current, j.previous
+	}
+	return j.current, ""
 }
 
 func (j *jwtGenerator) Generate(claims map[string]interface{}) (string, error) {
@@ -26,16 +54,16 @@ func (j *jwtGenerator) Generate(claims map[string]interface{}) (string, error) {
 	jwtClaims["exp"] = time.Now().Add(j.exp).Unix()
 	jwtClaims["iat"] = time.Now().Unix()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwtClaims)
-	return token.SignedString(j.secret)
+	current, _ := j.secrets()
+	return token.SignedString([]byte(current))
 }
 
 func (j *jwtGenerator) Validate(tokenStr string) (map[string]interface{}, error) {
-	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-		return j.secret, nil
-	})
+	current, previous := j.secrets()
+	token, err := parse(tokenStr, current)
+	if errors.Is(err, jwt.ErrTokenSignatureInvalid) && previous != "" {
+		token, err = parse(tokenStr, previous)
+	}
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
 			return nil, errors.New("token has expired")
@@ -50,3 +78,12 @@ func (j *jwtGenerator) Validate(tokenStr string) (map[string]interface{}, error)
 	}
 	return nil, errors.New("invalid token")
 }
+
+func parse(tokenStr, secret string) (*jwt.Token, error) {
+	return jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+}
@@ -0,0 +1,68 @@
+package token_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-web/internal/infra/token"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingJwtGenerator(t *testing.T) {
+	newGenerator := func(exp time.Duration) (*atomic.Value, func(claims map[string]any) string, func(tokenStr string) error) {
+		secret := new(atomic.Value)
+		secret.Store("secret-1")
+		gen := token.NewRotatingJwtGenerator(func() string { return secret.Load().(string) }, exp)
+		generate := func(claims map[string]any) string {
+			tokenStr, err := gen.Generate(claims)
+			require.NoError(t, err)
+			return tokenStr
+		}
+		validate := func(tokenStr string) error {
+			_, err := gen.Validate(tokenStr)
+			return err
+		}
+		return secret, generate, validate
+	}
+
+	t.Run("should accept tokens of the previous secret after a rotation", func(t *testing.T) {
+		secret, generate, validate := newGenerator(time.Minute)
+		old := generate(map[string]any{"sub": "1"})
+
+		secret.Store("secret-2")
+		assert.NoError(t, validate(old))
+		assert.NoError(t, validate(generate(map[string]any{"sub": "1"})))
+
+		secret.Store("secret-3")
+		assert.Error(t, validate(old), "only the previous secret is kept")
+	})
+
+	t.Run("should sign with the current secret only", func(t *testing.T) {
+		secret, generate, _ := newGenerator(time.Minute)
+		secret.Store("secret-2")
+		_, err := jwt.Parse(generate(map[string]any{"sub": "1"}), func(*jwt.Token) (any, error) {
+			return []byte("secret-2"), nil
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("should forget the previous secret after one token lifetime", func(t *testing.T) {
+		secret, _, validate := newGenerator(100 * time.Millisecond)
+		// Signed with the previous secret but valid for longer than the
+		// generator would ever issue, as a leaked secret would allow.
+		forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub": "1",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}).SignedString([]byte("secret-1"))
+		require.NoError(t, err)
+
+		secret.Store("secret-2")
+		assert.NoError(t, validate(forged))
+		time.Sleep(150 * time.Millisecond)
+		assert.Error(t, validate(forged))
+	})
+}
@@ -1,56 +1,940 @@
 package platform
 
-import "fmt"
+import (
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"go-web/internal/shared"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultJwtSecret = "default_secret"
+
+// FeatureSignup toggles the registration of new accounts.
+const FeatureSignup = "signup"
+
+// Config is resolved in the following order, later sources overriding earlier ones:
+//
+//  1. built-in defaults
+//  2. the YAML config file given by -config or CONFIG_FILE
+//  3. environment variables
+//  4. command line flags
+//
+// Only LogLevel, Debug, Limiter, Cors and Features are reloadable at runtime,
+// see ConfigWatcher.
 type Config struct {
-	HttpHost string
-	HttpPort string
+	Env      string `yaml:"env"`
+	Debug    bool   `yaml:"debug"`
+	LogLevel string `yaml:"log_level"`
+
+	// File is the config file the values were loaded from, if any.
+	File           string        `yaml:"-"`
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+
+	Http    HttpConfig    `yaml:"http"`
+	Monitor MonitorConfig `yaml:"monitor"`
+	Store   StoreConfig   `yaml:"store"`
+	Cache   CacheConfig   `yaml:"cache"`
+	Auth    AuthConfig    `yaml:"auth"`
+	Account AccountConfig `yaml:"account"`
+	Events  EventsConfig  `yaml:"events"`
+	Limiter LimiterConfig `yaml:"limiter"`
+	Secrets SecretsConfig `yaml:"secrets"`
+	Cors    CorsConfig    `yaml:"cors"`
+
+	SecurityHeaders SecurityHeadersConfig `yaml:"security_headers"`
+	Csrf            CsrfConfig            `yaml:"csrf"`
+	Compression     CompressionConfig     `yaml:"compression"`
+
+	Features map[string]bool `yaml:"features"`
+}
+
+type HttpConfig struct {
+	Host              string        `yaml:"host"`
+	Port              string        `yaml:"port"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes"`
+	TLS               TLSConfig     `yaml:"tls"`
+	HTTP2             HTTP2Config   `yaml:"http2"`
+}
+
+// HTTP2Config tunes HTTP/2, which is always offered over TLS. H2C also accepts
+// HTTP/2 over cleartext connections, for use behind a mesh. AltSvc, when set,
+// is advertised in the Alt-Svc header so clients can upgrade to an HTTP/3
+// endpoint served in front of this server, e.g. `h3=":443"; ma=86400`.
+type HTTP2Config struct {
+	H2C                  bool          `yaml:"h2c"`
+	MaxConcurrentStreams uint32        `yaml:"max_concurrent_streams"`
+	MaxReadFrameSize     uint32        `yaml:"max_read_frame_size"`
+	IdleTimeout          time.Duration `yaml:"idle_timeout"`
+	AltSvc               string        `yaml:"alt_svc"`
+}
+
+// TLSConfig enables native TLS. Certificates are reloaded from disk when the
+// files change. ClientAuth "request" or "require" turns on mutual TLS, with
+// client certificates verified against ClientCAFile.
+type TLSConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	CertFile       string        `yaml:"cert_file"`
+	KeyFile        string        `yaml:"key_file"`
+	MinVersion     string        `yaml:"min_version"`
+	CipherSuites   []string      `yaml:"cipher_suites"`
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+	ClientAuth     string        `yaml:"client_auth"`
+	ClientCAFile   string        `yaml:"client_ca_file"`
+}
+
+type MonitorConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Host    string `yaml:"host"`
+	Port    string `yaml:"port"`
+}
+
+// StoreConfig selects the store with Driver: postgres, sqlite or memory. The
+// connection settings are those of Postgres; SQLite only uses Path, a file or
+// ":memory:". The memory store loses everything on restart and is meant for
+// development and tests.
+type StoreConfig struct {
+	Driver   string `yaml:"driver"`
+	Path     string `yaml:"path"`
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Database string `yaml:"database"`
+	// TxIsolation is the isolation level of transactions: read_committed,
+	// repeatable_read or serializable. Empty uses the database default.
+	TxIsolation string `yaml:"tx_isolation"`
+	// TxMaxRetries is how many times a transaction aborted by a serialization
+	// failure is retried.
+	TxMaxRetries int `yaml:"tx_max_retries"`
+
+	SSLMode         string `yaml:"sslmode"`
+	ApplicationName string `yaml:"application_name"`
+	// StatementTimeout aborts statements running longer. 0 disables it.
+	StatementTimeout time.Duration `yaml:"statement_timeout"`
+	// MaxOpenConns of 0 leaves the pool unbounded.
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// ConnectTimeout is how long the server keeps retrying to reach the
+	// database at startup before giving up.
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+
+	// Replicas are the host:port of read replicas, reached with the same
+	// credentials as the primary. Reads that need not see the latest writes
+	// are spread over those that answered their last health check, run every
+	// ReplicaCheckInterval.
+	Replicas             []string      `yaml:"replicas"`
+	ReplicaCheckInterval time.Duration `yaml:"replica_check_interval"`
+
+	// SlowQueryThreshold logs the queries taking longer, without their
+	// arguments. 0 disables the log.
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
+}
+
+// CacheConfig selects the cache with Driver: memcached, or memory for a cache
+// private to the process.
+type CacheConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Driver  string `yaml:"driver"`
+	Host    string `yaml:"host"`
+	Port    string `yaml:"port"`
+}
+
+// AuthConfig AdminEmails are granted the admin role at startup, which is how
+// the first administrators are created. Accounts not registered yet are
+// skipped until the next start.
+type AuthConfig struct {
+	JwtSecret      string        `yaml:"jwt_secret"`
+	AccessTokenTTL time.Duration `yaml:"access_token_ttl"`
+	AdminEmails    []string      `yaml:"admin_emails"`
+}
+
+// AccountConfig controls the lifecycle of deleted accounts. They are purged
+// DeletionGracePeriod after deletion by a job running every PurgeInterval.
+// LowercaseEmails stores the local part of email addresses in lowercase too;
+// their domain always is.
+type AccountConfig struct {
+	DeletionGracePeriod time.Duration `yaml:"deletion_grace_period"`
+	PurgeInterval       time.Duration `yaml:"purge_interval"`
+	LowercaseEmails     bool          `yaml:"lowercase_emails"`
+}
+
+// EventsConfig selects where the domain events of the outbox are published
+// with Publisher: log, or webhook to post them to WebhookURL. The relay looks
+// for new events every RelayInterval and keeps the published ones for
+// Retention.
+type EventsConfig struct {
+	Publisher      string        `yaml:"publisher"`
+	WebhookURL     string        `yaml:"webhook_url"`
+	WebhookSecret  string        `yaml:"webhook_secret"`
+	WebhookTimeout time.Duration `yaml:"webhook_timeout"`
+	RelayInterval  time.Duration `yaml:"relay_interval"`
+	Retention      time.Duration `yaml:"retention"`
+}
+
+type LimiterConfig struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+// CorsConfig holds the default CORS policy and optional per route group
+// policies keyed by path prefix, e.g. "/api/auth/". Empty fields of a group
+// policy inherit the default policy.
+type CorsConfig struct {
+	CorsPolicy `yaml:",inline"`
+	Groups     map[string]CorsPolicy `yaml:"groups"`
+}
+
+// CorsPolicy origins may contain one wildcard, e.g. "https://*.example.com".
+type CorsPolicy struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers"`
+	ExposedHeaders   []string      `yaml:"exposed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
+}
+
+// GroupPolicy returns the policy of the group with the given prefix, filling
+// its empty fields from the default policy.
+func (c CorsConfig) GroupPolicy(prefix string) CorsPolicy {
+	p, ok := c.Groups[prefix]
+	if !ok {
+		return c.CorsPolicy
+	}
+	if len(p.AllowedOrigins) == 0 {
+		p.AllowedOrigins = c.AllowedOrigins
+	}
+	if len(p.AllowedMethods) == 0 {
+		p.AllowedMethods = c.AllowedMethods
+	}
+	if len(p.AllowedHeaders) == 0 {
+		p.AllowedHeaders = c.AllowedHeaders
+	}
+	if len(p.ExposedHeaders) == 0 {
+		p.ExposedHeaders = c.ExposedHeaders
+	}
+	if p.MaxAge == 0 {
+		p.MaxAge = c.MaxAge
+	}
+	return p
+}
+
+// SecurityHeadersConfig sets the common security headers on every response.
+// The Docs profile applies to the Swagger UI under /docs/, the Api profile to
+// everything else. Empty values leave the header unset.
+type SecurityHeadersConfig struct {
+	Enabled           bool            `yaml:"enabled"`
+	HSTS              HSTSConfig      `yaml:"hsts"`
+	NoSniff           bool            `yaml:"no_sniff"`
+	ReferrerPolicy    string          `yaml:"referrer_policy"`
+	PermissionsPolicy string          `yaml:"permissions_policy"`
+	Api               SecurityProfile `yaml:"api"`
+	Docs              SecurityProfile `yaml:"docs"`
+}
+
+// HSTSConfig is only sent over TLS, or over a proxy reporting
+// X-Forwarded-Proto: https when TrustForwardedProto is set.
+type HSTSConfig struct {
+	Enabled             bool          `yaml:"enabled"`
+	MaxAge              time.Duration `yaml:"max_age"`
+	IncludeSubdomains   bool          `yaml:"include_subdomains"`
+	Preload             bool          `yaml:"preload"`
+	TrustForwardedProto bool          `yaml:"trust_forwarded_proto"`
+}
+
+type SecurityProfile struct {
+	FrameOptions          string `yaml:"frame_options"`
+	ContentSecurityPolicy string `yaml:"content_security_policy"`
+}
+
+// CompressionConfig controls response compression and the decoding of
+// compressed request bodies. Encodings lists the supported response encodings
+// by server preference; MaxRequestBytes applies to the decompressed body.
+type CompressionConfig struct {
+	Enabled         bool     `yaml:"enabled"`
+	Encodings       []string `yaml:"encodings"`
+	Level           int      `yaml:"level"`
+	MinSize         int      `yaml:"min_size"`
+	MaxRequestBytes int64    `yaml:"max_request_bytes"`
+}
+
+// CsrfConfig protects the cookie authenticated endpoints. Requests must echo the
+// csrfToken cookie in the X-CSRF-Token header and, when they carry an Origin,
+// come from the server itself or one of TrustedOrigins.
+type CsrfConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	TrustedOrigins []string `yaml:"trusted_origins"`
+}
+
+// SecretsConfig selects where runtime secrets (jwt_secret, store_password,
+// webhook_secret) are read from. Values found in the provider take precedence
+// over the plain config.
+type SecretsConfig struct {
+	Provider        string        `yaml:"provider"`
+	Dir             string        `yaml:"dir"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	Vault           VaultConfig   `yaml:"vault"`
+}
+
+type VaultConfig struct {
+	Addr  string `yaml:"addr"`
+	Token string `yaml:"token"`
+	Mount string `yaml:"mount"`
+	Path  string `yaml:"path"`
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Env:            "dev",
+		Debug:          true,
+		ReloadInterval: 5 * time.Second,
+		Http: HttpConfig{
+			Host:              "localhost",
+			Port:              "8000",
+			ReadTimeout:       5 * time.Second,
+			WriteTimeout:      10 * time.Second,
+			ReadHeaderTimeout: 2 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			MaxHeaderBytes:    http.DefaultMaxHeaderBytes,
+			HTTP2: HTTP2Config{
+				MaxConcurrentStreams: 250,
+			},
+			TLS: TLSConfig{
+				MinVersion:     "1.2",
+				ReloadInterval: time.Minute,
+				ClientAuth:     "none",
+			},
+		},
+		Monitor: MonitorConfig{
+			Enabled: true,
+			Host:    "localhost",
+			Port:    "9000",
+		},
+		Store: StoreConfig{
+			Driver:       "postgres",
+			Path:         "go-web.db",
+			Host:         "localhost",
+			Port:         "5432",
+			User:         "postgres",
+			Password:     "postgres",
+			Database:     "postgres",
+			TxMaxRetries: 3,
+
+			SSLMode:         "disable",
+			ApplicationName: "go-web",
+			MaxOpenConns:    100,
+			MaxIdleConns:    50,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectTimeout:  30 * time.Second,
+
+			ReplicaCheckInterval: 5 * time.Second,
+			SlowQueryThreshold:   200 * time.Millisecond,
+		},
+		Cache: CacheConfig{
+			Enabled: true,
+			Driver:  "memcached",
+			Host:    "localhost",
+			Port:    "11211",
+		},
+		Auth: AuthConfig{
+			JwtSecret:      defaultJwtSecret,
+			AccessTokenTTL: 5 * time.Minute,
+		},
+		Account: AccountConfig{
+			DeletionGracePeriod: 30 * 24 * time.Hour,
+			PurgeInterval:       time.Hour,
+		},
+		Events: EventsConfig{
+			Publisher:      "log",
+			WebhookTimeout: 10 * time.Second,
+			RelayInterval:  time.Second,
+			Retention:      7 * 24 * time.Hour,
+		},
+		Limiter: LimiterConfig{
+			Rate:  100000,
+			Burst: 300000,
+		},
+		SecurityHeaders: SecurityHeadersConfig{
+			Enabled: true,
+			HSTS: HSTSConfig{
+				Enabled:           true,
+				MaxAge:            180 * 24 * time.Hour,
+				IncludeSubdomains: true,
+			},
+			NoSniff:           true,
+			ReferrerPolicy:    "no-referrer",
+			PermissionsPolicy: "camera=(), microphone=(), geolocation=(), payment=()",
+			Api: SecurityProfile{
+				FrameOptions:          "DENY",
+				ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+			},
+			Docs: SecurityProfile{
+				FrameOptions:          "SAMEORIGIN",
+				ContentSecurityPolicy: "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data:; frame-ancestors 'self'",
+			},
+		},
+		Csrf: CsrfConfig{
+			Enabled: true,
+		},
+		Compression: CompressionConfig{
+			Enabled:         true,
+			Encodings:       []string{"zstd", "gzip"},
+			MinSize:         1024,
+			MaxRequestBytes: 1 << 20,
+		},
+		Secrets: SecretsConfig{
+			Provider:        "env",
+			Dir:             "/run/secrets",
+			RefreshInterval: time.Minute,
+			Vault: VaultConfig{
+				Mount: "secret",
+				Path:  "go-web",
+			},
+		},
+		Cors: CorsConfig{
+			CorsPolicy: CorsPolicy{
+				AllowedOrigins: []string{"*"},
+				AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead},
+				AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-Requested-With", "X-CSRF-Token", "Idempotency-Key", "If-Match", "If-None-Match"},
+				ExposedHeaders: []string{"ETag"},
+				MaxAge:         10 * time.Minute,
+			},
+		},
+		Features: map[string]bool{
+			FeatureSignup: true,
+		},
+	}
+}
+
+// LoadConfig builds the configuration from defaults, config file, environment
+// and the given command line arguments, then validates the result.
+func LoadConfig(args []string) (*Config, error) {
+	cfg := defaultConfig()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := fs.String("config", getEnvStr("CONFIG_FILE", ""), "path to a YAML config file")
+	fs.String("env", "", "runtime environment (dev, staging, prod)")
+	fs.Bool("debug", false, "enable debug logging")
+	fs.String("log-level", "", "log level (debug, info, warn, error), overrides -debug")
+	fs.String("http.host", "", "http server host")
+	fs.String("http.port", "", "http server port")
+	fs.Bool("monitor.enabled", false, "enable the monitor server")
+	fs.Bool("cache.enabled", false, "enable the cache")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *path != "" {
+		if err := cfg.loadFile(*path); err != nil {
+			return nil, err
+		}
+		cfg.File = *path
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.loadFlags(fs); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+	default:
+		return fmt.Errorf("config: unsupported file format %q", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	//nolint:errcheck
+	defer f.Close()
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config: parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv applies environment overrides. Secret values also accept a KEY_FILE
+// variable pointing to a file holding the value, e.g. JWT_SECRET_FILE.
+func (c *Config) loadEnv() error {
+	var env envReader
+
+	c.Env = getEnvStr("ENV", c.Env)
+	c.Debug = env.getBool("DEBUG", c.Debug)
+	c.LogLevel = getEnvStr("LOG_LEVEL", c.LogLevel)
+	c.ReloadInterval = env.getDuration("CONFIG_RELOAD_INTERVAL", c.ReloadInterval)
+
+	c.Http.Host = getEnvStr("HTTP_HOST", c.Http.Host)
+	c.Http.Port = env.getPort("HTTP_PORT", c.Http.Port)
+	c.Http.ReadTimeout = env.getDuration("HTTP_READ_TIMEOUT", c.Http.ReadTimeout)
+	c.Http.WriteTimeout = env.getDuration("HTTP_WRITE_TIMEOUT", c.Http.WriteTimeout)
+	c.Http.ReadHeaderTimeout = env.getDuration("HTTP_READ_HEADER_TIMEOUT", c.Http.ReadHeaderTimeout)
+	c.Http.IdleTimeout = env.getDuration("HTTP_IDLE_TIMEOUT", c.Http.IdleTimeout)
+	c.Http.MaxHeaderBytes = env.getInt("HTTP_MAX_HEADER_BYTES", c.Http.MaxHeaderBytes)
+	c.Http.HTTP2.H2C = env.getBool("HTTP2_H2C", c.Http.HTTP2.H2C)
+	c.Http.HTTP2.MaxConcurrentStreams = env.getUint32("HTTP2_MAX_CONCURRENT_STREAMS", c.Http.HTTP2.MaxConcurrentStreams)
+	c.Http.HTTP2.MaxReadFrameSize = env.getUint32("HTTP2_MAX_READ_FRAME_SIZE", c.Http.HTTP2.MaxReadFrameSize)
+	c.Http.HTTP2.IdleTimeout = env.getDuration("HTTP2_IDLE_TIMEOUT", c.Http.HTTP2.IdleTimeout)
+	c.Http.HTTP2.AltSvc = getEnvStr("HTTP_ALT_SVC", c.Http.HTTP2.AltSvc)
+	c.Http.TLS.Enabled = env.getBool("HTTP_TLS_ENABLED", c.Http.TLS.Enabled)
+	c.Http.TLS.CertFile = getEnvStr("HTTP_TLS_CERT_FILE", c.Http.TLS.CertFile)
+	c.Http.TLS.KeyFile = getEnvStr("HTTP_TLS_KEY_FILE", c.Http.TLS.KeyFile)
+	c.Http.TLS.MinVersion = getEnvStr("HTTP_TLS_MIN_VERSION", c.Http.TLS.MinVersion)
+	c.Http.TLS.CipherSuites = getEnvList("HTTP_TLS_CIPHER_SUITES", c.Http.TLS.CipherSuites)
+	c.Http.TLS.ReloadInterval = env.getDuration("HTTP_TLS_RELOAD_INTERVAL", c.Http.TLS.ReloadInterval)
+	c.Http.TLS.ClientAuth = getEnvStr("HTTP_TLS_CLIENT_AUTH", c.Http.TLS.ClientAuth)
+	c.Http.TLS.ClientCAFile = getEnvStr("HTTP_TLS_CLIENT_CA_FILE", c.Http.TLS.ClientCAFile)
+
+	c.Monitor.Enabled = env.getBool("MONITOR_ENABLED", c.Monitor.Enabled)
+	c.Monitor.Host = getEnvStr("MONITOR_HOST", c.Monitor.Host)
+	c.Monitor.Port = env.getPort("MONITOR_PORT", c.Monitor.Port)
+
+	c.Store.Driver = getEnvStr("STORE_DRIVER", c.Store.Driver)
+	c.Store.Path = getEnvStr("STORE_PATH", c.Store.Path)
+	c.Store.Host = getEnvStr("STORE_HOST", c.Store.Host)
+	c.Store.Port = getEnvStr("STORE_PORT", c.Store.Port)
+	c.Store.User = getEnvStr("STORE_USER", c.Store.User)
+	c.Store.Password = env.getSecret("STORE_PASSWORD", c.Store.Password)
+	c.Store.Database = getEnvStr("STORE_DB", c.Store.Database)
+	c.Store.TxIsolation = getEnvStr("STORE_TX_ISOLATION", c.Store.TxIsolation)
+	c.Store.TxMaxRetries = env.getInt("STORE_TX_MAX_RETRIES", c.Store.TxMaxRetries)
+	c.Store.SSLMode = getEnvStr("STORE_SSLMODE", c.Store.SSLMode)
+	c.Store.ApplicationName = getEnvStr("STORE_APPLICATION_NAME", c.Store.ApplicationName)
+	c.Store.StatementTimeout = env.getDuration("STORE_STATEMENT_TIMEOUT", c.Store.StatementTimeout)
+	c.Store.MaxOpenConns = env.getInt("STORE_MAX_OPEN_CONNS", c.Store.MaxOpenConns)
+	c.Store.MaxIdleConns = env.getInt("STORE_MAX_IDLE_CONNS", c.Store.MaxIdleConns)
+	c.Store.ConnMaxLifetime = env.getDuration("STORE_CONN_MAX_LIFETIME", c.Store.ConnMaxLifetime)
+	c.Store.ConnMaxIdleTime = env.getDuration("STORE_CONN_MAX_IDLE_TIME", c.Store.ConnMaxIdleTime)
+	c.Store.ConnectTimeout = env.getDuration("STORE_CONNECT_TIMEOUT", c.Store.ConnectTimeout)
+	c.Store.Replicas = getEnvList("STORE_REPLICAS", c.Store.Replicas)
+	c.Store.ReplicaCheckInterval = env.getDuration("STORE_REPLICA_CHECK_INTERVAL", c.Store.ReplicaCheckInterval)
+	c.Store.SlowQueryThreshold = env.getDuration("STORE_SLOW_QUERY_THRESHOLD", c.Store.SlowQueryThreshold)
 
-	MonitorHost string
-	MonitorPort string
+	c.Cache.Enabled = env.getBool("CACHE_ENABLED", c.Cache.Enabled)
+	c.Cache.Driver = getEnvStr("CACHE_DRIVER", c.Cache.Driver)
+	c.Cache.Host = getEnvStr("CACHE_HOST", c.Cache.Host)
+	c.Cache.Port = getEnvStr("CACHE_PORT", c.Cache.Port)
 
-	Env            string
-	Debug          bool
-	MonitorEnabled bool
-	CacheEnabled   bool
+	c.Auth.JwtSecret = env.getSecret("JWT_SECRET", c.Auth.JwtSecret)
+	c.Auth.AccessTokenTTL = env.getDuration("JWT_ACCESS_TOKEN_TTL", c.Auth.AccessTokenTTL)
+	c.Auth.AdminEmails = getEnvList("ADMIN_EMAILS", c.Auth.AdminEmails)
 
-	JwtSecret string
+	c.Account.DeletionGracePeriod = env.getDuration("ACCOUNT_DELETION_GRACE_PERIOD", c.Account.DeletionGracePeriod)
+	c.Account.PurgeInterval = env.getDuration("ACCOUNT_PURGE_INTERVAL", c.Account.PurgeInterval)
+	c.Account.LowercaseEmails = env.getBool("ACCOUNT_LOWERCASE_EMAILS", c.Account.LowercaseEmails)
+
+	c.Events.Publisher = getEnvStr("EVENTS_PUBLISHER", c.Events.Publisher)
+	c.Events.WebhookURL = getEnvStr("EVENTS_WEBHOOK_URL", c.Events.WebhookURL)
+	c.Events.WebhookSecret = env.getSecret("EVENTS_WEBHOOK_SECRET", c.Events.WebhookSecret)
+	c.Events.WebhookTimeout = env.getDuration("EVENTS_WEBHOOK_TIMEOUT", c.Events.WebhookTimeout)
+	c.Events.RelayInterval = env.getDuration("EVENTS_RELAY_INTERVAL", c.Events.RelayInterval)
+	c.Events.Retention = env.getDuration("EVENTS_RETENTION", c.Events.Retention)
+
+	c.Limiter.Rate = env.getFloat("LIMITER_RATE", c.Limiter.Rate)
+	c.Limiter.Burst = env.getInt("LIMITER_BURST", c.Limiter.Burst)
+
+	c.Cors.AllowedOrigins = getEnvList("CORS_ALLOWED_ORIGINS", c.Cors.AllowedOrigins)
+	c.Cors.AllowedMethods = getEnvList("CORS_ALLOWED_METHODS", c.Cors.AllowedMethods)
+	c.Cors.AllowedHeaders = getEnvList("CORS_ALLOWED_HEADERS", c.Cors.AllowedHeaders)
+	c.Cors.ExposedHeaders = getEnvList("CORS_EXPOSED_HEADERS", c.Cors.ExposedHeaders)
+	c.Cors.AllowCredentials = env.getBool("CORS_ALLOW_CREDENTIALS", c.Cors.AllowCredentials)
+	c.Cors.MaxAge = env.getDuration("CORS_MAX_AGE", c.Cors.MaxAge)
+
+	c.SecurityHeaders.Enabled = env.getBool("SECURITY_HEADERS_ENABLED", c.SecurityHeaders.Enabled)
+	c.SecurityHeaders.HSTS.Enabled = env.getBool("SECURITY_HSTS_ENABLED", c.SecurityHeaders.HSTS.Enabled)
+	c.SecurityHeaders.HSTS.MaxAge = env.getDuration("SECURITY_HSTS_MAX_AGE", c.SecurityHeaders.HSTS.MaxAge)
+	c.SecurityHeaders.HSTS.TrustForwardedProto = env.getBool("SECURITY_HSTS_TRUST_FORWARDED_PROTO", c.SecurityHeaders.HSTS.TrustForwardedProto)
+	c.SecurityHeaders.Api.ContentSecurityPolicy = getEnvStr("SECURITY_API_CSP", c.SecurityHeaders.Api.ContentSecurityPolicy)
+	c.SecurityHeaders.Docs.ContentSecurityPolicy = getEnvStr("SECURITY_DOCS_CSP", c.SecurityHeaders.Docs.ContentSecurityPolicy)
+
+	c.Csrf.Enabled = env.getBool("CSRF_ENABLED", c.Csrf.Enabled)
+	c.Csrf.TrustedOrigins = getEnvList("CSRF_TRUSTED_ORIGINS", c.Csrf.TrustedOrigins)
+
+	c.Compression.Enabled = env.getBool("COMPRESSION_ENABLED", c.Compression.Enabled)
+	c.Compression.Encodings = getEnvList("COMPRESSION_ENCODINGS", c.Compression.Encodings)
+	c.Compression.Level = env.getInt("COMPRESSION_LEVEL", c.Compression.Level)
+	c.Compression.MinSize = env.getInt("COMPRESSION_MIN_SIZE", c.Compression.MinSize)
+	c.Compression.MaxRequestBytes = int64(env.getInt("COMPRESSION_MAX_REQUEST_BYTES", int(c.Compression.MaxRequestBytes)))
+
+	c.Secrets.Provider = getEnvStr("SECRETS_PROVIDER", c.Secrets.Provider)
+	c.Secrets.Dir = getEnvStr("SECRETS_DIR", c.Secrets.Dir)
+	c.Secrets.RefreshInterval = env.getDuration("SECRETS_REFRESH_INTERVAL", c.Secrets.RefreshInterval)
+	c.Secrets.Vault.Addr = getEnvStr("VAULT_ADDR", c.Secrets.Vault.Addr)
+	c.Secrets.Vault.Token = env.getSecret("VAULT_TOKEN", c.Secrets.Vault.Token)
+	c.Secrets.Vault.Mount = getEnvStr("VAULT_MOUNT", c.Secrets.Vault.Mount)
+	c.Secrets.Vault.Path = getEnvStr("VAULT_PATH", c.Secrets.Vault.Path)
+	return env.err()
+}
+
+func (c *Config) loadFlags(fs *flag.FlagSet) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		val := f.Value.String()
+		switch f.Name {
+		case "env":
+			c.Env = val
+		case "debug":
+			c.Debug, err = strconv.ParseBool(val)
+		case "log-level":
+			c.LogLevel = val
+		case "http.host":
+			c.Http.Host = val
+		case "http.port":
+			c.Http.Port = val
+		case "monitor.enabled":
+			c.Monitor.Enabled, err = strconv.ParseBool(val)
+		case "cache.enabled":
+			c.Cache.Enabled, err = strconv.ParseBool(val)
+		}
+	})
+	return err
+}
+
+// Validate reports every invalid setting at once. In production it also
+// refuses insecure defaults so a misconfigured deployment fails to boot.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Env == "" {
+		errs = append(errs, errors.New("env is required"))
+	}
+	if c.LogLevel != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+			errs = append(errs, fmt.Errorf("log_level: %w", err))
+		}
+	}
+	if len(c.Cors.AllowedOrigins) == 0 {
+		errs = append(errs, errors.New("cors.allowed_origins must not be empty"))
+	}
+	for prefix, policy := range c.Cors.Groups {
+		if !strings.HasPrefix(prefix, "/") {
+			errs = append(errs, fmt.Errorf("cors.groups: prefix %q must start with /", prefix))
+		}
+		if policy.AllowCredentials && slices.Contains(c.Cors.GroupPolicy(prefix).AllowedOrigins, "*") {
+			errs = append(errs, fmt.Errorf("cors.groups.%s: credentials cannot be allowed for any origin", prefix))
+		}
+	}
+	if c.Cors.AllowCredentials && slices.Contains(c.Cors.AllowedOrigins, "*") {
+		errs = append(errs, errors.New("cors: credentials cannot be allowed for any origin"))
+	}
+	if err := validatePort(c.Http.Port); err != nil {
+		errs = append(errs, fmt.Errorf("http.port: %w", err))
+	}
+	if c.Monitor.Enabled {
+		if err := validatePort(c.Monitor.Port); err != nil {
+			errs = append(errs, fmt.Errorf("monitor.port: %w", err))
+		}
+	}
+	switch c.Store.Driver {
+	case "postgres":
+		errs = append(errs, c.Store.validatePostgres()...)
+	case "sqlite":
+		if c.Store.Path == "" {
+			errs = append(errs, errors.New("store.path is required for the sqlite driver"))
+		}
+	case "memory":
+	default:
+		errs = append(errs, fmt.Errorf("store.driver: unknown driver %q", c.Store.Driver))
+	}
+	switch c.Store.TxIsolation {
+	case "", "read_committed", "repeatable_read", "serializable":
+	default:
+		errs = append(errs, fmt.Errorf("store.tx_isolation: unknown isolation level %q", c.Store.TxIsolation))
+	}
+	if c.Store.TxMaxRetries < 0 {
+		errs = append(errs, errors.New("store.tx_max_retries must not be negative"))
+	}
+	if c.Cache.Enabled {
+		switch c.Cache.Driver {
+		case "memcached":
+			if err := validatePort(c.Cache.Port); err != nil {
+				errs = append(errs, fmt.Errorf("cache.port: %w", err))
+			}
+		case "memory":
+		default:
+			errs = append(errs, fmt.Errorf("cache.driver: unknown driver %q", c.Cache.Driver))
+		}
+	}
+	if c.Http.ReadTimeout <= 0 || c.Http.WriteTimeout <= 0 || c.Http.ReadHeaderTimeout <= 0 {
+		errs = append(errs, errors.New("http timeouts must be positive"))
+	}
+	if c.Http.IdleTimeout < 0 || c.Http.MaxHeaderBytes < 0 {
+		errs = append(errs, errors.New("http.idle_timeout and http.max_header_bytes must not be negative"))
+	}
+	if f := c.Http.HTTP2.MaxReadFrameSize; f != 0 && (f < 16<<10 || f > 1<<24-1) {
+		errs = append(errs, errors.New("http.http2.max_read_frame_size must be between 16KiB and 16MiB"))
+	}
+	if c.Http.TLS.Enabled {
+		errs = append(errs, c.Http.TLS.validate()...)
+	}
+	if c.Auth.JwtSecret == "" {
+		errs = append(errs, errors.New("auth.jwt_secret is required"))
+	}
+	if c.Account.DeletionGracePeriod < 0 {
+		errs = append(errs, errors.New("account.deletion_grace_period must not be negative"))
+	}
+	if c.Account.PurgeInterval <= 0 {
+		errs = append(errs, errors.New("account.purge_interval must be positive"))
+	}
+	switch c.Events.Publisher {
+	case "log":
+	case "webhook":
+		if u, err := url.Parse(c.Events.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("events.webhook_url: invalid url %q", c.Events.WebhookURL))
+		}
+		if c.Events.WebhookTimeout <= 0 {
+			errs = append(errs, errors.New("events.webhook_timeout must be positive"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("events.publisher: unknown publisher %q", c.Events.Publisher))
+	}
+	if c.Events.RelayInterval <= 0 || c.Events.Retention <= 0 {
+		errs = append(errs, errors.New("events.relay_interval and events.retention must be positive"))
+	}
+	if c.Auth.AccessTokenTTL <= 0 {
+		errs = append(errs, errors.New("auth.access_token_ttl must be positive"))
+	}
+	if c.Limiter.Rate <= 0 || c.Limiter.Burst <= 0 {
+		errs = append(errs, errors.New("limiter.rate and limiter.burst must be positive"))
+	}
+	for _, fo := range []string{c.SecurityHeaders.Api.FrameOptions, c.SecurityHeaders.Docs.FrameOptions} {
+		if fo != "" && fo != "DENY" && fo != "SAMEORIGIN" {
+			errs = append(errs, fmt.Errorf("security_headers: invalid frame_options %q", fo))
+		}
+	}
+	for _, enc := range c.Compression.Encodings {
+		if enc != "gzip" && enc != "zstd" {
+			errs = append(errs, fmt.Errorf("compression.encodings: unsupported encoding %q", enc))
+		}
+	}
+	if c.Compression.Level < 0 || c.Compression.Level > 9 {
+		errs = append(errs, errors.New("compression.level must be between 0 (default) and 9"))
+	}
+	if c.Compression.MaxRequestBytes <= 0 {
+		errs = append(errs, errors.New("compression.max_request_bytes must be positive"))
+	}
+	switch c.Secrets.Provider {
+	case "env":
+	case "file":
+		if c.Secrets.Dir == "" {
+			errs = append(errs, errors.New("secrets.dir is required for the file provider"))
+		}
+	case "vault":
+		if c.Secrets.Vault.Addr == "" || c.Secrets.Vault.Token == "" {
+			errs = append(errs, errors.New("secrets.vault.addr and secrets.vault.token are required for the vault provider"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("secrets.provider: unknown provider %q", c.Secrets.Provider))
+	}
+	if shared.IsProductionEnv(c.Env) && c.Store.Driver == "memory" {
+		errs = append(errs, errors.New("store.driver memory loses all data on restart and is not allowed in production"))
+	}
+	// Other providers resolve the real secrets at runtime, so the plain values
+	// are only fallbacks there and are checked by ValidateSecrets instead.
+	if shared.IsProductionEnv(c.Env) && c.Secrets.Provider == "env" {
+		errs = append(errs, c.insecureSecrets(c.Auth.JwtSecret, c.Store.Password, c.Events.WebhookSecret)...)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
 }
 
-func NewConfig() *Config {
-	cfg := &Config{
-		HttpHost:       getEnvStr("HTTP_HOST", "localhost"),
-		HttpPort:       getEnvStr("HTTP_PORT", "8000"),
-		MonitorHost:    getEnvStr("HTTP_HOST", "localhost"),
-		MonitorPort:    getEnvStr("HTTP_PORT", "9000"),
-		Env:            getEnvStr("ENV", "dev"),
-		Debug:          getEnvBool("DEBUG", true),
-		MonitorEnabled: getEnvBool("MONITOR_ENABLED", true),
-		CacheEnabled:   getEnvBool("CACHE_ENABLED", true),
-		JwtSecret:      getEnvStr("JWT_SECRET", "default_secret"),
+// ValidateSecrets checks the secrets once resolved by the secret provider. In
+// production it refuses those that are missing or left at their default,
+// whichever provider they came from.
+func (c *Config) ValidateSecrets(jwtSecret, storePassword, webhookSecret string) error {
+	if !shared.IsProductionEnv(c.Env) {
+		return nil
+	}
+	if errs := c.insecureSecrets(jwtSecret, storePassword, webhookSecret); len(errs) > 0 {
+		return fmt.Errorf("invalid secrets: %w", errors.Join(errs...))
 	}
-	return cfg
+	return nil
+}
+
+func (c *Config) insecureSecrets(jwtSecret, storePassword, webhookSecret string) []error {
+	var errs []error
+	if jwtSecret == defaultJwtSecret || len(jwtSecret) < 32 {
+		errs = append(errs, errors.New("auth.jwt_secret must be set to at least 32 characters in production"))
+	}
+	if c.Store.Driver == "postgres" && (storePassword == "" || storePassword == defaultConfig().Store.Password) {
+		errs = append(errs, errors.New("store.password must not use the default value in production"))
+	}
+	if c.Events.Publisher == "webhook" && webhookSecret == "" {
+		errs = append(errs, errors.New("events.webhook_secret is required in production"))
+	}
+	return errs
+}
+
+func (t TLSConfig) validate() []error {
+	var errs []error
+	if t.CertFile == "" || t.KeyFile == "" {
+		errs = append(errs, errors.New("http.tls.cert_file and http.tls.key_file are required"))
+	}
+	if t.MinVersion != "1.2" && t.MinVersion != "1.3" {
+		errs = append(errs, fmt.Errorf("http.tls.min_version: unsupported version %q", t.MinVersion))
+	}
+	for _, name := range t.CipherSuites {
+		if CipherSuiteID(name) == 0 {
+			errs = append(errs, fmt.Errorf("http.tls.cipher_suites: unknown or insecure cipher suite %q", name))
+		}
+	}
+	switch t.ClientAuth {
+	case "none":
+	case "request", "require":
+		if t.ClientCAFile == "" {
+			errs = append(errs, errors.New("http.tls.client_ca_file is required for mutual TLS"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("http.tls.client_auth: unknown mode %q", t.ClientAuth))
+	}
+	return errs
+}
+
+func (s StoreConfig) validatePostgres() []error {
+	var errs []error
+	if err := validatePort(s.Port); err != nil {
+		errs = append(errs, fmt.Errorf("store.port: %w", err))
+	}
+	if !slices.Contains([]string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}, s.SSLMode) {
+		errs = append(errs, fmt.Errorf("store.sslmode: unknown mode %q", s.SSLMode))
+	}
+	if s.MaxOpenConns < 0 || s.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("store.max_open_conns and store.max_idle_conns must not be negative"))
+	}
+	if s.MaxOpenConns > 0 && s.MaxIdleConns > s.MaxOpenConns {
+		errs = append(errs, errors.New("store.max_idle_conns must not exceed store.max_open_conns"))
+	}
+	if s.StatementTimeout < 0 || s.ConnMaxLifetime < 0 || s.ConnMaxIdleTime < 0 || s.ConnectTimeout < 0 || s.SlowQueryThreshold < 0 {
+		errs = append(errs, errors.New("store timeouts and lifetimes must not be negative"))
+	}
+	for _, replica := range s.Replicas {
+		_, port, err := net.SplitHostPort(replica)
+		if err == nil {
+			err = validatePort(port)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("store.replicas: %q: %w", replica, err))
+		}
+	}
+	if len(s.Replicas) > 0 && s.ReplicaCheckInterval <= 0 {
+		errs = append(errs, errors.New("store.replica_check_interval must be positive"))
+	}
+	return errs
+}
+
+// CipherSuiteID returns the id of a secure cipher suite by its standard name,
+// or 0 if there is none.
+func CipherSuiteID(name string) uint16 {
+	for _, cs := range tls.CipherSuites() {
+		if cs.Name == name {
+			return cs.ID
+		}
+	}
+	return 0
+}
+
+func validatePort(port string) error {
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
+
+// SlogLevel returns LogLevel when set, otherwise debug or info depending on Debug.
+func (c *Config) SlogLevel() slog.Level {
+	var level slog.Level
+	if c.LogLevel != "" && level.UnmarshalText([]byte(c.LogLevel)) == nil {
+		return level
+	}
+	if c.Debug {
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
+
+// FeatureEnabled reports whether a feature toggle is on. Toggles missing from
+// the config are off.
+func (c *Config) FeatureEnabled(name string) bool {
+	return c.Features[name]
 }
 
 func (c *Config) HttpServerAddr() string {
-	return fmt.Sprintf("%s:%s", c.HttpHost, c.HttpPort)
+	return fmt.Sprintf("%s:%s", c.Http.Host, c.Http.Port)
 }
 
 func (c *Config) MonitorServerAddr() string {
-	return fmt.Sprintf("%s:%s", c.MonitorHost, c.MonitorPort)
+	return fmt.Sprintf("%s:%s", c.Monitor.Host, c.Monitor.Port)
 }
 
 func (c *Config) StoreAddr() string {
-	host := getEnvStr("STORE_HOST", "localhost")
-	port := getEnvStr("STORE_PORT", "5432")
-	user := getEnvStr("STORE_USER", "postgres")
-	password := getEnvStr("STORE_PASSWORD", "postgres")
-	db := getEnvStr("STORE_DB", "postgres")
-	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", user, password, host, port, db)
+	return c.StoreAddrWithPassword(c.Store.Password)
+}
+
+// StoreAddrWithPassword builds the store DSN with a password that may have
+// been rotated since the config was loaded.
+func (c *Config) StoreAddrWithPassword(password string) string {
+	return c.storeDSN(net.JoinHostPort(c.Store.Host, c.Store.Port), password)
+}
+
+// ReplicaAddrWithPassword is StoreAddrWithPassword for the replica at
+// host:port, one of Store.Replicas.
+func (c *Config) ReplicaAddrWithPassword(replica, password string) string {
+	return c.storeDSN(replica, password)
+}
+
+func (c *Config) storeDSN(host, password string) string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(c.Store.User, password),
+		Host:   host,
+		Path:   c.Store.Database,
+	}
+	q := url.Values{"sslmode": {c.Store.SSLMode}}
+	if c.Store.ApplicationName != "" {
+		q.Set("application_name", c.Store.ApplicationName)
+	}
+	if c.Store.StatementTimeout > 0 {
+		// Sent as a run-time parameter of the session, in milliseconds.
+		q.Set("statement_timeout", strconv.FormatInt(c.Store.StatementTimeout.Milliseconds(), 10))
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
 }
 
 func (c *Config) CacheAddr() string {
-	host := getEnvStr("CACHE_HOST", "localhost")
-	port := getEnvStr("CACHE_PORT", "11211")
-	return fmt.Sprintf("%s:%s", host, port)
+	return fmt.Sprintf("%s:%s", c.Cache.Host, c.Cache.Port)
 }
@@ -2,4 +2,7 @@ package platform
 
 type ContextKey string
 
-const CtxUserKey ContextKey = "user"
+const (
+	CtxUserKey   ContextKey = "user"
+	CtxClientKey ContextKey = "client"
+)
@@ -1,8 +1,12 @@
 package platform
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func getEnvStr(key string, fallback string) string {
@@ -13,11 +17,95 @@ func getEnvStr(key string, fallback string) string {
 	}
 }
 
-func getEnvBool(key string, fallback bool) bool {
+// envReader parses typed variables. A malformed value is collected as an
+// error naming the variable rather than replaced by the fallback, so that a
+// typo such as STORE_CONNECT_TIMEOUT=5 fails at startup.
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) parse(key string, parse func(val string) error) {
 	if val, exist := os.LookupEnv(key); exist {
-		valBool, _ := strconv.ParseBool(val)
-		return valBool
-	} else {
-		return fallback
+		if err := parse(val); err != nil {
+			e.errs = append(e.errs, fmt.Errorf("config: %s: %w", key, err))
+		}
+	}
+}
+
+func (e *envReader) getBool(key string, fallback bool) bool {
+	e.parse(key, func(val string) (err error) {
+		fallback, err = strconv.ParseBool(val)
+		return err
+	})
+	return fallback
+}
+
+func (e *envReader) getInt(key string, fallback int) int {
+	e.parse(key, func(val string) (err error) {
+		fallback, err = strconv.Atoi(val)
+		return err
+	})
+	return fallback
+}
+
+func (e *envReader) getFloat(key string, fallback float64) float64 {
+	e.parse(key, func(val string) (err error) {
+		fallback, err = strconv.ParseFloat(val, 64)
+		return err
+	})
+	return fallback
+}
+
+func (e *envReader) getDuration(key string, fallback time.Duration) time.Duration {
+	e.parse(key, func(val string) (err error) {
+		fallback, err = time.ParseDuration(val)
+		return err
+	})
+	return fallback
+}
+
+func (e *envReader) getUint32(key string, fallback uint32) uint32 {
+	e.parse(key, func(val string) error {
+		n, err := strconv.ParseUint(val, 10, 32)
+		fallback = uint32(n)
+		return err
+	})
+	return fallback
+}
+
+func (e *envReader) getPort(key string, fallback string) string {
+	e.parse(key, func(val string) error {
+		fallback = val
+		return validatePort(val)
+	})
+	return fallback
+}
+
+func (e *envReader) err() error {
+	return errors.Join(e.errs...)
+}
+
+func getEnvList(key string, fallback []string) []string {
+	if val, exist := os.LookupEnv(key); exist {
+		var list []string
+		for _, item := range strings.Split(val, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list
 	}
+	return fallback
+}
+
+// getSecret reads key, or the file named by key_FILE when it is set, so that
+// secrets can be mounted rather than passed in the environment.
+func (e *envReader) getSecret(key string, fallback string) string {
+	secret := getEnvStr(key, fallback)
+	e.parse(key+"_FILE", func(path string) error {
+		data, err := os.ReadFile(path)
+		secret = strings.TrimRight(string(data), "\r\n")
+		return err
+	})
+	return secret
 }
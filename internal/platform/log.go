@@ -5,13 +5,14 @@ import (
 	"os"
 )
 
+// LogLevel is shared by every logger built by NewLogger so the level can be
+// changed at runtime.
+var LogLevel = new(slog.LevelVar)
+
 func NewLogger(cfg *Config) *slog.Logger {
-	level := slog.LevelInfo
-	if cfg.Debug {
-		level = slog.LevelDebug
-	}
+	LogLevel.Set(cfg.SlogLevel())
 	if cfg.Env == "prod" {
-		return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+		return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: LogLevel}))
 	}
-	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: LogLevel}))
 }
@@ -0,0 +1,162 @@
+package platform
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ConfigWatcher reloads the configuration when the config file changes or the
+// process receives SIGHUP. Only the reloadable fields are swapped in; changes
+// to any other field are reported and ignored until the next restart.
+type ConfigWatcher struct {
+	args    []string
+	current atomic.Pointer[Config]
+	modTime time.Time
+
+	mu          sync.Mutex
+	subscribers []func(old, new *Config)
+}
+
+func NewConfigWatcher(cfg *Config, args []string) *ConfigWatcher {
+	w := &ConfigWatcher{args: args}
+	w.current.Store(cfg)
+	w.modTime = fileModTime(cfg.File)
+	return w
+}
+
+func (w *ConfigWatcher) Config() *Config {
+	return w.current.Load()
+}
+
+// FeatureEnabled reads a feature toggle of the current config, so that
+// callers holding on to the watcher see reloaded toggles.
+func (w *ConfigWatcher) FeatureEnabled(name string) bool {
+	return w.Config().FeatureEnabled(name)
+}
+
+// OnReload registers fn to be called after every successful reload.
+func (w *ConfigWatcher) OnReload(fn func(old, new *Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+func (w *ConfigWatcher) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if cfg := w.Config(); cfg.File != "" && cfg.ReloadInterval > 0 {
+		ticker := time.NewTicker(cfg.ReloadInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			slog.Info("received SIGHUP, reloading config")
+			//nolint:errcheck
+			w.Reload()
+		case <-tick:
+			if mod := fileModTime(w.Config().File); mod.After(w.modTime) {
+				w.modTime = mod
+				slog.Info("config file changed, reloading config", "file", w.Config().File)
+				//nolint:errcheck
+				w.Reload()
+			}
+		}
+	}
+}
+
+// Reload loads and validates the configuration again. An invalid config is
+// rejected and the previous one is kept.
+func (w *ConfigWatcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	next, err := LoadConfig(w.args)
+	if err != nil {
+		slog.Error("rejected config reload, keeping previous config", "error", err.Error())
+		return err
+	}
+	old := w.current.Load()
+	merged := *old
+	merged.Debug = next.Debug
+	merged.LogLevel = next.LogLevel
+	merged.Limiter = next.Limiter
+	merged.Cors = next.Cors
+	merged.Features = next.Features
+
+	changed := diffFields("", *old, merged, nil)
+	var ignored []string
+	for _, field := range diffFields("", *old, *next, nil) {
+		if !slices.Contains(changed, field) {
+			ignored = append(ignored, field)
+		}
+	}
+	if len(ignored) > 0 {
+		slog.Warn("config changes require a restart", "fields", strings.Join(ignored, ","))
+	}
+	if len(changed) == 0 {
+		slog.Info("config reloaded, nothing changed")
+		return nil
+	}
+
+	w.current.Store(&merged)
+	LogLevel.Set(merged.SlogLevel())
+	for _, fn := range w.subscribers {
+		fn(old, &merged)
+	}
+	slog.Info("config reloaded", "changed", strings.Join(changed, ","))
+	return nil
+}
+
+// diffFields lists the yaml paths of fields that differ between a and b.
+func diffFields(prefix string, a, b any, out []string) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	t := va.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("yaml"), ",")
+		fa, fb := va.Field(i), vb.Field(i)
+		if slices.Contains(tag[1:], "inline") {
+			out = diffFields(prefix, fa.Interface(), fb.Interface(), out)
+			continue
+		}
+		if tag[0] == "" || tag[0] == "-" {
+			continue
+		}
+		path := prefix + tag[0]
+		if f.Type.Kind() == reflect.Struct && f.Type != reflect.TypeOf(time.Time{}) {
+			out = diffFields(path+".", fa.Interface(), fb.Interface(), out)
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			out = append(out, path)
+		}
+	}
+	return out
+}
+
+func fileModTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,51 @@
+package platform_test
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	"go-web/internal/platform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigWatcher_Reload(t *testing.T) {
+	path := writeConfigFile(t, "log_level: info\nlimiter:\n    rate: 10\n    burst: 20\n")
+	args := []string{"-config", path}
+	cfg, err := platform.LoadConfig(args)
+	require.NoError(t, err)
+	watcher := platform.NewConfigWatcher(cfg, args)
+
+	var calls int
+	watcher.OnReload(func(old, new *platform.Config) { calls++ })
+
+	t.Run("should swap reloadable fields", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(`
+log_level: warn
+limiter:
+    rate: 5
+    burst: 6
+http:
+    port: "9999"
+features:
+    signup: true
+`), 0o600))
+		require.NoError(t, watcher.Reload())
+		got := watcher.Config()
+		assert.Equal(t, 5.0, got.Limiter.Rate)
+		assert.Equal(t, 6, got.Limiter.Burst)
+		assert.True(t, got.FeatureEnabled("signup"))
+		assert.Equal(t, slog.LevelWarn, platform.LogLevel.Level())
+		assert.Equal(t, "8000", got.Http.Port, "non reloadable fields must be kept")
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("should keep previous config on invalid reload", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("limiter:\n    rate: -1\n"), 0o600))
+		assert.Error(t, watcher.Reload())
+		assert.Equal(t, 5.0, watcher.Config().Limiter.Rate)
+		assert.Equal(t, 1, calls)
+	})
+}
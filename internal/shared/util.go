@@ -1,6 +1,8 @@
 package shared
 
 import (
+	crand "crypto/rand"
+	"encoding/base64"
 	"math/rand"
 	"time"
 )
@@ -17,6 +19,19 @@ func RandString(n int) string {
 	return string(b)
 }
 
+// SecureToken returns a URL safe token built from n cryptographically random bytes.
+func SecureToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 func IsDevelopmentEnv(env string) bool {
 	return env == "dev" || env == "development"
 }
+
+func IsProductionEnv(env string) bool {
+	return env == "prod" || env == "production"
+}
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"net/http"
+
+	domain "go-web/internal/core/models"
+	rest "go-web/internal/transport/http/models"
+)
+
+var userListSpec = listSpec{
+	filters: map[string]queryParam{
+		"email":         {field: domain.UserFieldEmail, op: domain.OpPrefix},
+		"status":        {field: domain.UserFieldStatus, op: domain.OpEq, parse: oneOf(domain.UserActive, domain.UserDisabled, domain.UserDeleted)},
+		"role":          {field: domain.UserFieldRole, op: domain.OpEq},
+		"createdAfter":  {field: domain.UserFieldCreatedAt, op: domain.OpGte, parse: parseTimeParam},
+		"createdBefore": {field: domain.UserFieldCreatedAt, op: domain.OpLt, parse: parseTimeParam},
+	},
+	sorts: map[string]func(string) (any, error){
+		domain.UserFieldCreatedAt: parseCursorTime,
+		domain.UserFieldEmail:     nil,
+	},
+	defaultSort: "-" + domain.UserFieldCreatedAt,
+}
+
+// listUsers godoc
+//
+//	@Summary		List users
+//	@Description	Returns a page of users matching the filters. Pass the nextCursor of a page as cursor, with the same filters and sort, to read the next one.
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			limit			query		int							false	"Page size, 1 to 100"	default(20)
+//	@Param			cursor			query		string						false	"nextCursor of the previous page"
+//	@Param			email			query		string						false	"Email prefix"
+//	@Param			status			query		string						false	"Account status"	Enums(active, disabled, deleted)
+//	@Param			role			query		string						false	"Role the users must have"
+//	@Param			createdAfter	query		string						false	"RFC 3339 time, inclusive"
+//	@Param			createdBefore	query		string						false	"RFC 3339 time, exclusive"
+//	@Param			sort			query		string						false	"Sort field, prefixed with - for descending order"	Enums(created_at, -created_at, email, -email)	default(-created_at)
+//	@Success		200				{object}	models.ListUsersResponseBody	"Page of users"
+//	@Failure		400				{object}	models.ErrorResponseBody		"Invalid or unknown query parameter"
+//	@Failure		401				{object}	models.ErrorResponseBody		"Invalid or expired token"
+//	@Failure		403				{object}	models.ErrorResponseBody		"Not an administrator"
+//	@Failure		500				{object}	models.ErrorResponseBody		"Internal server error"
+//	@Router			/admin/users [get]
+func (h *apiHandler) listUsers(w http.ResponseWriter, r *http.Request) {
+	query, err := parseListQuery(r, userListSpec)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	page, err := h.admin.ListUsers(r.Context(), *query)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondSuccess(w, http.StatusOK, newListResponse(query, page, func(u *domain.User) rest.AdminUser {
+		return *toAdminUser(u)
+	}))
+}
+
+// getUser godoc
+//
+//	@Summary		Get a user
+//	@Description	Returns a user whatever their status, deleted accounts included
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			id	path		string						true	"User id"
+//	@Success		200	{object}	models.AdminUserResponseBody	"User"
+//	@Failure		401	{object}	models.ErrorResponseBody		"Invalid or expired token"
+//	@Failure		403	{object}	models.ErrorResponseBody		"Not an administrator"
+//	@Failure		404	{object}	models.ErrorResponseBody		"User not found"
+//	@Failure		500	{object}	models.ErrorResponseBody		"Internal server error"
+//	@Router			/admin/users/{id} [get]
+func (h *apiHandler) getUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.admin.GetUser(r.Context(), r.PathValue("id"))
+	h.respondAdminUser(w, user, err)
+}
+
+// disableUser godoc
+//
+//	@Summary		Disable a user
+//	@Description	Keeps the user from logging in and revokes their sessions. Disabling a disabled user does nothing.
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			id	path		string						true	"User id"
+//	@Success		200	{object}	models.AdminUserResponseBody	"Disabled user"
+//	@Failure		401	{object}	models.ErrorResponseBody		"Invalid or expired token"
+//	@Failure		403	{object}	models.ErrorResponseBody		"Not an administrator, or own account"
+//	@Failure		404	{object}	models.ErrorResponseBody		"User not found"
+//	@Failure		500	{object}	models.ErrorResponseBody		"Internal server error"
+//	@Router			/admin/users/{id}/disable [post]
+func (h *apiHandler) disableUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.admin.DisableUser(r.Context(), requestUser(r), r.PathValue("id"))
+	h.respondAdminUser(w, user, err)
+}
+
+// enableUser godoc
+//
+//	@Summary		Enable a user
+//	@Description	Lets a disabled user log in again
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			id	path		string						true	"User id"
+//	@Success		200	{object}	models.AdminUserResponseBody	"Enabled user"
+//	@Failure		401	{object}	models.ErrorResponseBody		"Invalid or expired token"
+//	@Failure		403	{object}	models.ErrorResponseBody		"Not an administrator"
+//	@Failure		404	{object}	models.ErrorResponseBody		"User not found"
+//	@Failure		500	{object}	models.ErrorResponseBody		"Internal server error"
+//	@Router			/admin/users/{id}/enable [post]
+func (h *apiHandler) enableUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.admin.EnableUser(r.Context(), requestUser(r), r.PathValue("id"))
+	h.respondAdminUser(w, user, err)
+}
+
+// logoutUser godoc
+//
+//	@Summary		Log a user out everywhere
+//	@Description	Revokes every refresh token of the user. Access tokens already issued stay valid until they expire.
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			id	path		string							true	"User id"
+//	@Success		200	{object}	models.AdminLogoutResponseBody	"Sessions revoked"
+//	@Failure		401	{object}	models.ErrorResponseBody		"Invalid or expired token"
+//	@Failure		403	{object}	models.ErrorResponseBody		"Not an administrator"
+//	@Failure		404	{object}	models.ErrorResponseBody		"User not found"
+//	@Failure		500	{object}	models.ErrorResponseBody		"Internal server error"
+//	@Router			/admin/users/{id}/logout [post]
+func (h *apiHandler) logoutUser(w http.ResponseWriter, r *http.Request) {
+	if err := h.admin.LogoutUser(r.Context(), requestUser(r), r.PathValue("id")); err != nil {
+		respondError(w, err)
+		return
+	}
+	respondSuccess(
+		w,
+		http.StatusOK,
+		&rest.AdminLogoutResponseBody{Data: nil, StatusCode: http.StatusOK},
+	)
+}
+
+// resetTwoFactor godoc
+//
+//	@Summary		Reset two-factor authentication
+//	@Description	Turns two-factor authentication off for a user who lost their second factor
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			id	path		string						true	"User id"
+//	@Success		200	{object}	models.AdminUserResponseBody	"Updated user"
+//	@Failure		401	{object}	models.ErrorResponseBody		"Invalid or expired token"
+//	@Failure		403	{object}	models.ErrorResponseBody		"Not an administrator"
+//	@Failure		404	{object}	models.ErrorResponseBody		"User not found"
+//	@Failure		500	{object}	models.ErrorResponseBody		"Internal server error"
+//	@Router			/admin/users/{id}/two-factor/reset [post]
+func (h *apiHandler) resetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, err := h.admin.ResetTwoFactor(r.Context(), requestUser(r), r.PathValue("id"))
+	h.respondAdminUser(w, user, err)
+}
+
+func (h *apiHandler) respondAdminUser(w http.ResponseWriter, user *domain.User, err error) {
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondSuccess(
+		w,
+		http.StatusOK,
+		&rest.AdminUserResponseBody{Data: toAdminUser(user), StatusCode: http.StatusOK},
+	)
+}
+
+func toAdminUser(user *domain.User) *rest.AdminUser {
+	return &rest.AdminUser{
+		UserProfile: *toUserProfile(user),
+		Status:      user.Status(),
+		DisabledAt:  user.DisabledAt,
+		DeletedAt:   user.DeletedAt,
+	}
+}
@@ -0,0 +1,320 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-web/internal/core/models"
+	"go-web/internal/platform"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+	Flush() error
+}
+
+type compressor struct {
+	encoders map[string]*sync.Pool
+	gzipDec  sync.Pool
+	zstdDec  sync.Pool
+	cfg      platform.CompressionConfig
+}
+
+// CompressionMiddleware compresses responses with the best encoding accepted
+// by the client and decodes gzip or zstd request bodies. Small bodies and
+// content types that are already compressed are sent as is.
+func CompressionMiddleware(cfg platform.CompressionConfig) func(next http.Handler) http.Handler {
+	c := newCompressor(cfg)
+	return func(next http.Handler) http.Handler {
+		if !cfg.Enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := c.decodeRequest(w, r); err != nil {
+				respondError(w, err)
+				return
+			}
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), cfg.Encodings)
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, c: c, encoding: encoding, status: http.StatusOK}
+			//nolint:errcheck
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+func newCompressor(cfg platform.CompressionConfig) *compressor {
+	c := &compressor{cfg: cfg, encoders: make(map[string]*sync.Pool)}
+	c.encoders["gzip"] = &sync.Pool{New: func() any {
+		level := gzip.DefaultCompression
+		if cfg.Level > 0 {
+			level = cfg.Level
+		}
+		w, _ := gzip.NewWriterLevel(nil, level)
+		return w
+	}}
+	c.encoders["zstd"] = &sync.Pool{New: func() any {
+		level := zstd.SpeedDefault
+		if cfg.Level > 0 {
+			level = zstd.EncoderLevelFromZstd(cfg.Level)
+		}
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(1))
+		return w
+	}}
+	c.gzipDec.New = func() any { return new(gzip.Reader) }
+	c.zstdDec.New = func() any {
+		d, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(cfg.MaxRequestBytes)))
+		return d
+	}
+	return c
+}
+
+// decodeRequest replaces a compressed body by its decoded content. The size
+// limit applies to the decoded bytes so small compressed bombs are rejected.
+func (c *compressor) decodeRequest(w http.ResponseWriter, r *http.Request) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	var body io.ReadCloser
+	switch enc := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); enc {
+	case "", "identity":
+		body = r.Body
+	case "gzip", "x-gzip":
+		zr := c.gzipDec.Get().(*gzip.Reader)
+		if err := zr.Reset(r.Body); err != nil {
+			c.gzipDec.Put(zr)
+			return models.InvalidBody("Invalid gzip request body", err)
+		}
+		body = &decodedBody{Reader: zr, src: r.Body, release: func() { c.gzipDec.Put(zr) }}
+	case "zstd":
+		zr := c.zstdDec.Get().(*zstd.Decoder)
+		if err := zr.Reset(r.Body); err != nil {
+			c.zstdDec.Put(zr)
+			return models.InvalidBody("Invalid zstd request body", err)
+		}
+		body = &decodedBody{Reader: zr, src: r.Body, release: func() {
+			//nolint:errcheck
+			zr.Reset(nil)
+			c.zstdDec.Put(zr)
+		}}
+	default:
+		return models.Unsupported("Unsupported Content-Encoding "+enc, nil)
+	}
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	if body != r.Body {
+		r.ContentLength = -1
+	}
+	r.Body = http.MaxBytesReader(w, body, c.cfg.MaxRequestBytes)
+	return nil
+}
+
+type decodedBody struct {
+	io.Reader
+	src     io.Closer
+	release func()
+	once    sync.Once
+}
+
+func (b *decodedBody) Close() error {
+	b.once.Do(b.release)
+	return b.src.Close()
+}
+
+// negotiateEncoding picks the supported encoding with the highest quality in
+// the Accept-Encoding header, preferring the order of supported on ties. It
+// returns "" when the response should not be encoded.
+func negotiateEncoding(header string, supported []string) string {
+	if header == "" {
+		return ""
+	}
+	qualities := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if name == "*" {
+			wildcard = q
+		} else if name != "" {
+			qualities[name] = q
+		}
+	}
+	best, bestQ := "", 0.0
+	for _, enc := range supported {
+		q, ok := qualities[enc]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the first MinSize bytes to decide whether the
+// response is worth compressing before any header is sent.
+type compressWriter struct {
+	http.ResponseWriter
+	c        *compressor
+	encoding string
+	status   int
+	buf      []byte
+	enc      encoder
+	decided  bool
+	wrote    bool
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+	w.status = code
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.c.cfg.MinSize {
+			return len(p), nil
+		}
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// start sends the headers, compressing when allowed and the response is
+// eligible, then writes out the buffered bytes.
+func (w *compressWriter) start(allowed bool) error {
+	w.decided = true
+	h := w.Header()
+	if allowed && w.compressible() {
+		w.enc = w.c.encoders[w.encoding].Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+func (w *compressWriter) compressible() bool {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || w.status < http.StatusOK ||
+		w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+	ct := h.Get("Content-Type")
+	if ct == "" {
+		ct = http.DetectContentType(w.buf)
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	return !isCompressedType(mt)
+}
+
+var compressedTypes = []string{
+	"application/gzip",
+	"application/zip",
+	"application/zstd",
+	"application/x-7z-compressed",
+	"application/x-bzip2",
+	"application/x-rar-compressed",
+	"application/x-xz",
+	"application/pdf",
+	"application/octet-stream",
+	"font/woff",
+	"font/woff2",
+}
+
+func isCompressedType(mt string) bool {
+	if mt == "image/svg+xml" {
+		return false
+	}
+	if strings.HasPrefix(mt, "image/") || strings.HasPrefix(mt, "video/") || strings.HasPrefix(mt, "audio/") {
+		return true
+	}
+	return slices.Contains(compressedTypes, mt)
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		//nolint:errcheck
+		w.start(true)
+	}
+	if w.enc != nil {
+		//nolint:errcheck
+		w.enc.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Close flushes a response that stayed below MinSize uncompressed, or
+// finishes the encoded stream and returns the encoder to its pool.
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		return w.start(false)
+	}
+	if w.enc == nil {
+		return nil
+	}
+	err := w.enc.Close()
+	w.enc.Reset(nil)
+	w.c.encoders[w.encoding].Put(w.enc)
+	w.enc = nil
+	return err
+}
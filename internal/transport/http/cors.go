@@ -0,0 +1,85 @@
+package http
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go-web/internal/platform"
+
+	"github.com/rs/cors"
+)
+
+type corsGroup struct {
+	prefix  string
+	handler http.Handler
+}
+
+// corsRouter applies the policy of the longest matching route group, or the
+// default policy when no group matches.
+type corsRouter struct {
+	def    http.Handler
+	groups []corsGroup
+}
+
+// CorsHandler applies the CORS policies of the watched config to next, and
+// swaps them in place when a reload changes them.
+func CorsHandler(watcher *platform.ConfigWatcher, next http.Handler) http.Handler {
+	h := newSwapHandler(newCorsHandler(watcher.Config().Cors, next))
+	watcher.OnReload(func(old, new *platform.Config) {
+		if !reflect.DeepEqual(old.Cors, new.Cors) {
+			h.Swap(newCorsHandler(new.Cors, next))
+		}
+	})
+	return h
+}
+
+func newCorsHandler(cfg platform.CorsConfig, next http.Handler) http.Handler {
+	r := &corsRouter{def: newCors(cfg.CorsPolicy).Handler(next)}
+	for prefix := range cfg.Groups {
+		r.groups = append(r.groups, corsGroup{
+			prefix:  prefix,
+			handler: newCors(cfg.GroupPolicy(prefix)).Handler(next),
+		})
+	}
+	sort.Slice(r.groups, func(i, j int) bool {
+		return len(r.groups[i].prefix) > len(r.groups[j].prefix)
+	})
+	return r
+}
+
+func newCors(p platform.CorsPolicy) *cors.Cors {
+	return cors.New(cors.Options{
+		AllowedOrigins:   p.AllowedOrigins,
+		AllowedMethods:   p.AllowedMethods,
+		AllowedHeaders:   p.AllowedHeaders,
+		ExposedHeaders:   p.ExposedHeaders,
+		AllowCredentials: p.AllowCredentials,
+		MaxAge:           int(p.MaxAge.Seconds()),
+		Logger:           corsLogger{},
+	})
+}
+
+func (c *corsRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, g := range c.groups {
+		if strings.HasPrefix(r.URL.Path, g.prefix) {
+			g.handler.ServeHTTP(w, r)
+			return
+		}
+	}
+	c.def.ServeHTTP(w, r)
+}
+
+// corsLogger forwards only rejected preflights, the rest of the rs/cors
+// output is too noisy even at debug level.
+type corsLogger struct{}
+
+func (corsLogger) Printf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if reason, ok := strings.CutPrefix(strings.TrimSpace(msg), "Preflight aborted: "); ok {
+		slog.Debug("cors preflight rejected", "reason", reason)
+	}
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"go-web/internal/core/models"
+	"go-web/internal/shared"
+)
+
+const (
+	csrfCookieName = "csrfToken"
+	csrfHeaderName = "X-CSRF-Token"
+	csrfCookiePath = "/api/auth"
+)
+
+// issueCsrfToken sets a fresh double-submit token as a cookie. The same token
+// is returned to the client in the response body, which a cross-site page
+// cannot read, and must be sent back in the X-CSRF-Token header.
+func (h *apiHandler) issueCsrfToken(w http.ResponseWriter) (string, error) {
+	token, err := shared.SecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    token,
+		Path:     csrfCookiePath,
+		Secure:   !shared.IsDevelopmentEnv(h.env),
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+		Expires:  time.Now().Add(7 * 24 * time.Hour),
+	})
+	return token, nil
+}
+
+func (h *apiHandler) clearCsrfToken(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    "",
+		Path:     csrfCookiePath,
+		Secure:   !shared.IsDevelopmentEnv(h.env),
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+		MaxAge:   -1,
+	})
+}
+
+// csrfProtect guards endpoints authenticated by cookies. It rejects requests
+// from untrusted origins and requests whose X-CSRF-Token header does not match
+// the csrfToken cookie.
+func (h *apiHandler) csrfProtect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.csrf.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !h.isTrustedOrigin(r) {
+			respondError(w, models.Forbidden("Cross-site request rejected", nil))
+			return
+		}
+		cookie, err := r.Cookie(csrfCookieName)
+		header := r.Header.Get(csrfHeaderName)
+		if err != nil || cookie.Value == "" || header == "" ||
+			subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+			respondError(w, models.Forbidden("CSRF token missing or invalid", err))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isTrustedOrigin prefers the Origin header and falls back to Sec-Fetch-Site
+// for clients that do not send one.
+func (h *apiHandler) isTrustedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return r.Header.Get("Sec-Fetch-Site") != "cross-site"
+	}
+	if slices.Contains(h.csrf.TrustedOrigins, origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
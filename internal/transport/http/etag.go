@@ -0,0 +1,175 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"go-web/internal/core/models"
+)
+
+// maxETagBody is the largest body buffered to be hashed into an ETag. Larger
+// responses, such as exports, are streamed without one.
+const maxETagBody = 1 << 20 // 1MiB
+
+// ETagMiddleware adds a strong ETag, the hash of the encoded body, to
+// successful GET and HEAD responses that do not carry one and answers a
+// matching If-None-Match with 304 Not Modified. Responses whose handler set
+// their own ETag are not buffered, nor are those larger than maxETagBody or
+// flushed by their handler.
+func ETagMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ew := &etagWriter{ResponseWriter: w, ifNoneMatch: r.Header.Get("If-None-Match")}
+		next.ServeHTTP(ew, r)
+		ew.finish()
+	})
+}
+
+// bodyETag returns a strong entity tag for body.
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// versionETag returns a strong entity tag for a resource version kept by the
+// store, so that handlers can check preconditions without encoding the body.
+func versionETag(version string) string {
+	return bodyETag([]byte(version))
+}
+
+// CheckIfMatch enforces optimistic concurrency on mutating endpoints. A request
+// without If-Match is allowed; one whose If-Match does not match the current
+// entity tag is rejected with 412 Precondition Failed. current must be a
+// version tag, the same for every representation of the resource: compression
+// weakens it on the way out, so the W/ prefix is ignored here.
+func CheckIfMatch(r *http.Request, current string) error {
+	header := r.Header.Get("If-Match")
+	if header == "" || etagMatches(header, current) {
+		return nil
+	}
+	return models.PreconditionFailed("Resource has been modified", nil)
+}
+
+// etagMatches reports whether etag is listed in an If-Match or If-None-Match
+// header value, using the weak comparison of RFC 9110.
+func etagMatches(header, etag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// etagWriter holds a successful response back until it is complete, so that
+// its ETag can be computed from the body, unless it turns out to be streamed.
+type etagWriter struct {
+	http.ResponseWriter
+	ifNoneMatch string
+	status      int
+	body        bytes.Buffer
+	wrote       bool
+	// streaming is set once the response is passed through as it is written.
+	streaming bool
+	// notModified is set once 304 was sent and the body must be dropped.
+	notModified bool
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+	w.status = code
+	if code != http.StatusOK {
+		w.stream()
+		return
+	}
+	if etag := w.Header().Get("ETag"); etag != "" {
+		if etagMatches(w.ifNoneMatch, etag) {
+			w.sendNotModified()
+			return
+		}
+		w.stream()
+	}
+}
+
+func (w *etagWriter) Write(p []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	switch {
+	case w.notModified:
+		return len(p), nil
+	case w.streaming:
+		return w.ResponseWriter.Write(p)
+	case w.body.Len()+len(p) > maxETagBody:
+		w.stream()
+		return w.ResponseWriter.Write(p)
+	}
+	return w.body.Write(p)
+}
+
+// Flush gives up on the ETag since the handler wants the body sent now.
+func (w *etagWriter) Flush() {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.notModified && !w.streaming {
+		w.stream()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// stream sends the headers and what was buffered, then passes the rest of
+// the body through.
+func (w *etagWriter) stream() {
+	w.streaming = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.body.Len() > 0 {
+		//nolint:errcheck
+		w.ResponseWriter.Write(w.body.Bytes())
+		w.body.Reset()
+	}
+}
+
+func (w *etagWriter) sendNotModified() {
+	w.notModified = true
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(http.StatusNotModified)
+}
+
+// finish sends a buffered response with the ETag of its body.
+func (w *etagWriter) finish() {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.streaming || w.notModified {
+		return
+	}
+	etag := bodyETag(w.body.Bytes())
+	w.Header().Set("ETag", etag)
+	if etagMatches(w.ifNoneMatch, etag) {
+		w.sendNotModified()
+		return
+	}
+	w.stream()
+}
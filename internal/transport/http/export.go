@@ -0,0 +1,61 @@
+package http
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"time"
+
+	domain "go-web/internal/core/models"
+	rest "go-web/internal/transport/http/models"
+)
+
+func toUserExport(export *domain.UserExport) *rest.UserExport {
+	data := &rest.UserExport{
+		Profile:      toUserProfile(export.User),
+		Sessions:     []rest.SessionInfo{},
+		AuditEntries: []rest.AuditEntryInfo{},
+		ExportedAt:   time.Now().UTC(),
+	}
+	for _, s := range export.Sessions {
+		data.Sessions = append(data.Sessions, rest.SessionInfo{Id: s.Id, CreatedAt: s.CreatedAt})
+	}
+	for _, e := range export.Audit {
+		data.AuditEntries = append(data.AuditEntries, rest.AuditEntryInfo{
+			Id:        e.Id,
+			Action:    e.Action,
+			Detail:    e.Detail,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+	return data
+}
+
+// zipExport packs each part of the export in its own JSON file.
+func zipExport(data *rest.UserExport) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct {
+		name    string
+		content any
+	}{
+		{"profile.json", data.Profile},
+		{"sessions.json", data.Sessions},
+		{"audit.json", data.AuditEntries},
+	}
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: data.ExportedAt})
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
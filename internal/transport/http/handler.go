@@ -1,13 +1,14 @@
 package http
 
 import (
-	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"go-web/internal/core/ports"
 	"go-web/internal/core/service"
+	"go-web/internal/platform"
 	"go-web/internal/shared"
 
 	domain "go-web/internal/core/models"
@@ -18,20 +19,29 @@ import (
 
 type apiHandler struct {
 	auth      ports.AuthService
+	users     ports.UserService
+	admin     ports.AdminService
 	validator ports.Validator
 	cache     ports.Cache
 	limiter   ports.RateLimiter
 
-	env string
+	env  string
+	csrf platform.CsrfConfig
+	// features reads the feature toggles of the current config.
+	features func(name string) bool
 }
 
 // This constructor is for test purpose only
-func NewApiHandler(auth ports.AuthService, validator ports.Validator, cache ports.Cache, limiter ports.RateLimiter) *apiHandler {
+func NewApiHandler(auth ports.AuthService, users ports.UserService, admin ports.AdminService, validator ports.Validator, cache ports.Cache, limiter ports.RateLimiter, features func(name string) bool) *apiHandler {
 	return &apiHandler{
 		auth:      auth,
+		users:     users,
+		admin:     admin,
 		validator: validator,
 		cache:     cache,
 		limiter:   limiter,
+		csrf:      platform.CsrfConfig{Enabled: true},
+		features:  features,
 	}
 }
 
@@ -47,11 +57,23 @@ func (h *apiHandler) RegisterRoutes(mux *http.ServeMux) {
 	apiMux := http.NewServeMux()
 	apiMux.HandleFunc("GET /example", h.helloWorld)
 	apiMux.HandleFunc("GET /error", h.giveError)
-	apiMux.HandleFunc("POST /auth/register", h.register)
+	apiMux.Handle("POST /auth/register", h.requireFeature(platform.FeatureSignup, h.idempotent(http.HandlerFunc(h.register))))
 	apiMux.HandleFunc("POST /auth/login", h.login)
-	apiMux.Handle("POST /auth/refresh", http.HandlerFunc(h.refresh))
-	apiMux.Handle("POST /auth/logout", h.authorize(http.HandlerFunc(h.logout)))
+	apiMux.Handle("POST /auth/refresh", h.csrfProtect(http.HandlerFunc(h.refresh)))
+	apiMux.Handle("POST /auth/logout", h.csrfProtect(h.authorize(http.HandlerFunc(h.logout))))
 	apiMux.Handle("GET /me", h.authorize(http.HandlerFunc(h.me)))
+	apiMux.Handle("PATCH /me", h.authorize(http.HandlerFunc(h.updateMe)))
+	apiMux.Handle("DELETE /me", h.authorize(http.HandlerFunc(h.deleteMe)))
+	apiMux.Handle("GET /me/export", h.authorize(http.HandlerFunc(h.exportMe)))
+	apiMux.Handle("POST /me/password", h.authorize(http.HandlerFunc(h.changePassword)))
+	apiMux.Handle("POST /me/email", h.authorize(http.HandlerFunc(h.changeEmail)))
+	apiMux.HandleFunc("POST /auth/email/confirm", h.confirmEmail)
+	apiMux.Handle("GET /admin/users", h.authorizeAdmin(h.listUsers))
+	apiMux.Handle("GET /admin/users/{id}", h.authorizeAdmin(h.getUser))
+	apiMux.Handle("POST /admin/users/{id}/disable", h.authorizeAdmin(h.disableUser))
+	apiMux.Handle("POST /admin/users/{id}/enable", h.authorizeAdmin(h.enableUser))
+	apiMux.Handle("POST /admin/users/{id}/logout", h.authorizeAdmin(h.logoutUser))
+	apiMux.Handle("POST /admin/users/{id}/two-factor/reset", h.authorizeAdmin(h.resetTwoFactor))
 	mux.Handle("/api/", http.StripPrefix("/api", apiMux))
 	mux.Handle("/docs/", httpSwagger.WrapHandler)
 }
@@ -93,18 +115,22 @@ func (h *apiHandler) giveError(w http.ResponseWriter, r *http.Request) {
 //	@Tags			Auth
 //	@Accept			json
 //	@Produce		json
-//	@Param			payload	body		models.RegisterRequestBody	true	"User's credentials"
-//	@Success		201		{object}	models.RegisterResponseBody	"User created successfully"
-//	@Failure		500		{object}	models.ErrorResponseBody	"Internal server error"
+//	@Param			payload			body		models.RegisterRequestBody	true	"User's credentials"
+//	@Param			Idempotency-Key	header		string						false	"Makes retries return the first response"
+//	@Success		201				{object}	models.RegisterResponseBody	"User created successfully"
+//	@Failure		403				{object}	models.ErrorResponseBody	"Sign up is disabled"
+//	@Failure		409				{object}	models.ErrorResponseBody	"Email taken or same key in progress"
+//	@Failure		500				{object}	models.ErrorResponseBody	"Internal server error"
 //	@Router			/auth/register [post]
 func (h *apiHandler) register(w http.ResponseWriter, r *http.Request) {
 	var req rest.RegisterRequestBody
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeBody(w, r, &req); err != nil {
 		respondError(w, domain.InvalidBody("Invalid request body", err))
 		return
 	}
 	if err := h.validator.Validate(req); err != nil {
 		respondError(w, domain.InvalidBody("Invalid request body", err))
+		return
 	}
 	user, err := h.auth.Register(r.Context(), req.Email, req.Password)
 	if err != nil {
@@ -141,32 +167,30 @@ func (h *apiHandler) register(w http.ResponseWriter, r *http.Request) {
 //	@Router			/auth/login [post]
 func (h *apiHandler) login(w http.ResponseWriter, r *http.Request) {
 	var req rest.LoginRequestBody
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeBody(w, r, &req); err != nil {
 		respondError(w, domain.InvalidBody("Invalid request body", err))
 		return
 	}
 	if err := h.validator.Validate(req); err != nil {
 		respondError(w, domain.InvalidBody("Invalid request body", err))
+		return
 	}
 	tokens, err := h.auth.Login(r.Context(), req.Email, req.Password)
 	if err != nil {
 		respondError(w, err)
 		return
 	}
-	data := &rest.LoginResponse{Token: tokens.AccessToken, Type: "Bearer"}
+	csrfToken, err := h.issueCsrfToken(w)
+	if err != nil {
+		respondError(w, domain.Internal(err))
+		return
+	}
+	data := &rest.LoginResponse{Token: tokens.AccessToken, Type: "Bearer", CsrfToken: csrfToken}
 	resp := &rest.LoginResponseBody{
 		Data:       data,
 		StatusCode: http.StatusOK,
 	}
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refreshToken",
-		Value:    tokens.RefreshToken,
-		Path:     "/auth/refresh",
-		Secure:   !shared.IsDevelopmentEnv(h.env),
-		HttpOnly: true,
-		SameSite: http.SameSiteNoneMode,
-		Expires:  time.Now().Add(7 * 24 * time.Hour),
-	})
+	h.setRefreshTokenCookie(w, tokens.RefreshToken)
 	respondSuccess(
 		w,
 		http.StatusOK,
@@ -180,9 +204,11 @@ func (h *apiHandler) login(w http.ResponseWriter, r *http.Request) {
 //	@Description	Refreshes the JWT tokens using a valid refresh token
 //	@Tags			Auth
 //	@Produce		json
-//	@Success		200	{object}	models.RefreshTokenResponseBody	"Token refreshed successfully"
-//	@Failure		400	{object}	models.ErrorResponseBody			"Invalid request"
-//	@Failure		401	{object}	models.ErrorResponseBody			"Invalid or expired refresh token"
+//	@Param			X-CSRF-Token	header		string							true	"CSRF token returned by login or the previous refresh"
+//	@Success		200				{object}	models.RefreshTokenResponseBody	"Token refreshed successfully"
+//	@Failure		400				{object}	models.ErrorResponseBody		"Invalid request"
+//	@Failure		401				{object}	models.ErrorResponseBody		"Invalid or expired refresh token"
+//	@Failure		403				{object}	models.ErrorResponseBody		"Missing or invalid CSRF token"
 //	@Failure		500	{object}	models.ErrorResponseBody			"Internal server error"
 //	@Router			/auth/refresh [post]
 func (h *apiHandler) refresh(w http.ResponseWriter, r *http.Request) {
@@ -196,20 +222,17 @@ func (h *apiHandler) refresh(w http.ResponseWriter, r *http.Request) {
 		respondError(w, err)
 		return
 	}
-	data := &rest.LoginResponse{Token: tokens.AccessToken, Type: "Bearer"}
+	csrfToken, err := h.issueCsrfToken(w)
+	if err != nil {
+		respondError(w, domain.Internal(err))
+		return
+	}
+	data := &rest.LoginResponse{Token: tokens.AccessToken, Type: "Bearer", CsrfToken: csrfToken}
 	resp := &rest.RefreshTokenResponseBody{
 		Data:       data,
 		StatusCode: http.StatusOK,
 	}
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refreshToken",
-		Value:    tokens.RefreshToken,
-		Path:     "/auth/refresh",
-		Secure:   !shared.IsDevelopmentEnv(h.env),
-		HttpOnly: true,
-		SameSite: http.SameSiteNoneMode,
-		Expires:  time.Now().Add(7 * 24 * time.Hour),
-	})
+	h.setRefreshTokenCookie(w, tokens.RefreshToken)
 	respondSuccess(
 		w,
 		http.StatusOK,
@@ -223,9 +246,10 @@ func (h *apiHandler) refresh(w http.ResponseWriter, r *http.Request) {
 //	@Description	Logs out a user by invalidating their refresh token
 //	@Tags			Auth
 //	@Produce		json
-//	@Param			payload	body		models.LogoutRequestBody	true	"Refresh token to invalidate"
-//	@Success		200		{object}	models.LogoutResponseBody	"Logout successful"
-//	@Failure		400		{object}	models.ErrorResponseBody	"Invalid request body"
+//	@Param			X-CSRF-Token	header		string						true	"CSRF token returned by login or refresh"
+//	@Success		200				{object}	models.LogoutResponseBody	"Logout successful"
+//	@Failure		401				{object}	models.ErrorResponseBody	"Missing refresh token or access token"
+//	@Failure		403				{object}	models.ErrorResponseBody	"Missing or invalid CSRF token"
 //	@Failure		500		{object}	models.ErrorResponseBody	"Internal server error"
 //	@Router			/auth/logout [post]
 func (h *apiHandler) logout(w http.ResponseWriter, r *http.Request) {
@@ -238,15 +262,8 @@ func (h *apiHandler) logout(w http.ResponseWriter, r *http.Request) {
 		respondError(w, err)
 		return
 	}
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refreshToken",
-		Value:    "",
-		Path:     "/auth/refresh",
-		HttpOnly: true,
-		Secure:   !shared.IsDevelopmentEnv(h.env),
-		SameSite: http.SameSiteNoneMode,
-		MaxAge:   -1,
-	})
+	h.clearRefreshTokenCookie(w)
+	h.clearCsrfToken(w)
 	respondSuccess(
 		w,
 		http.StatusOK,
@@ -254,10 +271,336 @@ func (h *apiHandler) logout(w http.ResponseWriter, r *http.Request) {
 	)
 }
 
+// me godoc
+//
+//	@Summary		Get the current user
+//	@Description	Returns the profile of the user identified by the bearer token
+//	@Tags			User
+//	@Produce		json
+//	@Param			If-None-Match	header		string						false	"ETag of a cached profile"
+//	@Success		200				{object}	models.GetMeResponseBody	"Current user profile"
+//	@Success		304				"Profile not modified"
+//	@Failure		401				{object}	models.ErrorResponseBody	"Invalid or expired token"
+//	@Failure		404				{object}	models.ErrorResponseBody	"User not found"
+//	@Failure		500				{object}	models.ErrorResponseBody	"Internal server error"
+//	@Router			/me [get]
 func (h *apiHandler) me(w http.ResponseWriter, r *http.Request) {
+	// Users read their own writes: a replica could return a profile and an
+	// ETag older than their last change.
+	user, err := h.users.Get(ports.WithPrimary(r.Context()), requestUser(r))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	w.Header().Set("ETag", userETag(user))
 	respondSuccess(
 		w,
 		http.StatusOK,
-		&rest.GetMeResponseBody{Data: "me", StatusCode: http.StatusOK},
+		&rest.GetMeResponseBody{Data: toUserProfile(user), StatusCode: http.StatusOK},
 	)
 }
+
+// updateMe godoc
+//
+//	@Summary		Update the current user
+//	@Description	Updates the editable profile fields of the user identified by the bearer token
+//	@Tags			User
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload		body		models.UpdateMeRequestBody	true	"Profile fields to change"
+//	@Param			If-Match	header		string						false	"ETag of the profile being edited"
+//	@Success		200			{object}	models.UpdateMeResponseBody	"Updated user profile"
+//	@Failure		400			{object}	models.ErrorResponseBody	"Invalid request body"
+//	@Failure		401			{object}	models.ErrorResponseBody	"Invalid or expired token"
+//	@Failure		404			{object}	models.ErrorResponseBody	"User not found"
+//	@Failure		412			{object}	models.ErrorResponseBody	"Profile was modified since it was read"
+//	@Failure		500			{object}	models.ErrorResponseBody	"Internal server error"
+//	@Router			/me [patch]
+func (h *apiHandler) updateMe(w http.ResponseWriter, r *http.Request) {
+	var req rest.UpdateMeRequestBody
+	if err := decodeBody(w, r, &req); err != nil {
+		respondError(w, domain.InvalidBody("Invalid request body", err))
+		return
+	}
+	if err := h.validator.Validate(req); err != nil {
+		respondError(w, domain.InvalidBody("Invalid request body", err))
+		return
+	}
+	// The ETag is checked against the latest version, not that of a replica.
+	user, err := h.users.Get(ports.WithPrimary(r.Context()), requestUser(r))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	if err := CheckIfMatch(r, userETag(user)); err != nil {
+		respondError(w, err)
+		return
+	}
+	update := domain.ProfileUpdate{
+		DisplayName: req.DisplayName,
+		AvatarURL:   req.AvatarURL,
+		Locale:      req.Locale,
+		Timezone:    req.Timezone,
+	}
+	user, err = h.users.UpdateProfile(r.Context(), user, update)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	w.Header().Set("ETag", userETag(user))
+	respondSuccess(
+		w,
+		http.StatusOK,
+		&rest.UpdateMeResponseBody{Data: toUserProfile(user), StatusCode: http.StatusOK},
+	)
+}
+
+// deleteMe godoc
+//
+//	@Summary		Delete the current user
+//	@Description	Deletes the account after the password is confirmed and revokes all of its sessions. The data is purged for good once the grace period is over.
+//	@Tags			User
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		models.DeleteMeRequestBody	true	"Current password"
+//	@Success		200		{object}	models.DeleteMeResponseBody	"Account deleted"
+//	@Failure		400		{object}	models.ErrorResponseBody	"Invalid request body"
+//	@Failure		401		{object}	models.ErrorResponseBody	"Invalid or expired token"
+//	@Failure		403		{object}	models.ErrorResponseBody	"Password is incorrect"
+//	@Failure		404		{object}	models.ErrorResponseBody	"User not found"
+//	@Failure		500		{object}	models.ErrorResponseBody	"Internal server error"
+//	@Router			/me [delete]
+func (h *apiHandler) deleteMe(w http.ResponseWriter, r *http.Request) {
+	var req rest.DeleteMeRequestBody
+	if err := decodeBody(w, r, &req); err != nil {
+		respondError(w, domain.InvalidBody("Invalid request body", err))
+		return
+	}
+	if err := h.validator.Validate(req); err != nil {
+		respondError(w, domain.InvalidBody("Invalid request body", err))
+		return
+	}
+	if err := h.auth.DeleteAccount(r.Context(), requestUser(r), req.Password); err != nil {
+		respondError(w, err)
+		return
+	}
+	h.clearRefreshTokenCookie(w)
+	h.clearCsrfToken(w)
+	respondSuccess(
+		w,
+		http.StatusOK,
+		&rest.DeleteMeResponseBody{Data: nil, StatusCode: http.StatusOK},
+	)
+}
+
+// exportMe godoc
+//
+//	@Summary		Export the current user's data
+//	@Description	Returns everything stored about the user: profile, sessions and audit entries. Use format=zip to download them as a ZIP archive of JSON files.
+//	@Tags			User
+//	@Produce		json,application/zip
+//	@Param			format	query		string						false	"json (default) or zip"
+//	@Success		200		{object}	models.ExportMeResponseBody	"User data"
+//	@Failure		400		{object}	models.ErrorResponseBody	"Unknown format"
+//	@Failure		401		{object}	models.ErrorResponseBody	"Invalid or expired token"
+//	@Failure		404		{object}	models.ErrorResponseBody	"User not found"
+//	@Failure		500		{object}	models.ErrorResponseBody	"Internal server error"
+//	@Router			/me/export [get]
+func (h *apiHandler) exportMe(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		respondError(w, domain.InvalidParam("format must be json or zip", nil))
+		return
+	}
+	export, err := h.users.Export(r.Context(), requestUser(r))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	data := toUserExport(export)
+	w.Header().Set("Cache-Control", "no-store")
+	if format != "zip" {
+		respondSuccess(w, http.StatusOK, &rest.ExportMeResponseBody{Data: data, StatusCode: http.StatusOK})
+		return
+	}
+	archive, err := zipExport(data)
+	if err != nil {
+		respondError(w, domain.Internal(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="export-`+export.User.Id+`.zip"`)
+	w.WriteHeader(http.StatusOK)
+	//nolint:errcheck
+	w.Write(archive)
+}
+
+// changePassword godoc
+//
+//	@Summary		Change the password
+//	@Description	Changes the password of the current user. Every other session is revoked and new tokens are returned for this one.
+//	@Tags			User
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		models.ChangePasswordRequestBody	true	"Current and new password"
+//	@Success		200		{object}	models.ChangePasswordResponseBody	"Password changed"
+//	@Failure		400		{object}	models.ErrorResponseBody			"Invalid body or password rejected by the policy"
+//	@Failure		401		{object}	models.ErrorResponseBody			"Invalid or expired token"
+//	@Failure		403		{object}	models.ErrorResponseBody			"Current password is incorrect"
+//	@Failure		500		{object}	models.ErrorResponseBody			"Internal server error"
+//	@Router			/me/password [post]
+func (h *apiHandler) changePassword(w http.ResponseWriter, r *http.Request) {
+	var req rest.ChangePasswordRequestBody
+	if err := decodeBody(w, r, &req); err != nil {
+		respondError(w, domain.InvalidBody("Invalid request body", err))
+		return
+	}
+	if err := h.validator.Validate(req); err != nil {
+		respondError(w, domain.InvalidBody("Invalid request body", err))
+		return
+	}
+	tokens, err := h.auth.ChangePassword(r.Context(), requestUser(r), req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	csrfToken, err := h.issueCsrfToken(w)
+	if err != nil {
+		respondError(w, domain.Internal(err))
+		return
+	}
+	h.setRefreshTokenCookie(w, tokens.RefreshToken)
+	respondSuccess(
+		w,
+		http.StatusOK,
+		&rest.ChangePasswordResponseBody{
+			Data:       &rest.LoginResponse{Token: tokens.AccessToken, Type: "Bearer", CsrfToken: csrfToken},
+			StatusCode: http.StatusOK,
+		},
+	)
+}
+
+// changeEmail godoc
+//
+//	@Summary		Change the email address
+//	@Description	Sends a confirmation token to the new address and a notice to the current one. The address changes once the token is confirmed.
+//	@Tags			User
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		models.ChangeEmailRequestBody	true	"Current password and new email"
+//	@Success		202		{object}	models.ChangeEmailResponseBody	"Confirmation sent"
+//	@Failure		400		{object}	models.ErrorResponseBody		"Invalid request body"
+//	@Failure		401		{object}	models.ErrorResponseBody		"Invalid or expired token"
+//	@Failure		403		{object}	models.ErrorResponseBody		"Password is incorrect"
+//	@Failure		409		{object}	models.ErrorResponseBody		"Email already in use"
+//	@Failure		500		{object}	models.ErrorResponseBody		"Internal server error"
+//	@Router			/me/email [post]
+func (h *apiHandler) changeEmail(w http.ResponseWriter, r *http.Request) {
+	var req rest.ChangeEmailRequestBody
+	if err := decodeBody(w, r, &req); err != nil {
+		respondError(w, domain.InvalidBody("Invalid request body", err))
+		return
+	}
+	if err := h.validator.Validate(req); err != nil {
+		respondError(w, domain.InvalidBody("Invalid request body", err))
+		return
+	}
+	if err := h.auth.RequestEmailChange(r.Context(), requestUser(r), req.Password, req.NewEmail); err != nil {
+		respondError(w, err)
+		return
+	}
+	respondSuccess(
+		w,
+		http.StatusAccepted,
+		&rest.ChangeEmailResponseBody{Data: nil, StatusCode: http.StatusAccepted},
+	)
+}
+
+// confirmEmail godoc
+//
+//	@Summary		Confirm an email change
+//	@Description	Switches the account to the new address with the token sent to it. All sessions of the account are revoked.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		models.ConfirmEmailRequestBody	true	"Confirmation token"
+//	@Success		200		{object}	models.ConfirmEmailResponseBody	"Email changed"
+//	@Failure		400		{object}	models.ErrorResponseBody		"Invalid or expired token"
+//	@Failure		409		{object}	models.ErrorResponseBody		"Email already in use"
+//	@Failure		500		{object}	models.ErrorResponseBody		"Internal server error"
+//	@Router			/auth/email/confirm [post]
+func (h *apiHandler) confirmEmail(w http.ResponseWriter, r *http.Request) {
+	var req rest.ConfirmEmailRequestBody
+	if err := decodeBody(w, r, &req); err != nil {
+		respondError(w, domain.InvalidBody("Invalid request body", err))
+		return
+	}
+	if err := h.validator.Validate(req); err != nil {
+		respondError(w, domain.InvalidBody("Invalid request body", err))
+		return
+	}
+	if err := h.auth.ConfirmEmailChange(r.Context(), req.Token); err != nil {
+		respondError(w, err)
+		return
+	}
+	respondSuccess(
+		w,
+		http.StatusOK,
+		&rest.ConfirmEmailResponseBody{Data: nil, StatusCode: http.StatusOK},
+	)
+}
+
+func (h *apiHandler) setRefreshTokenCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refreshToken",
+		Value:    token,
+		Path:     csrfCookiePath,
+		Secure:   !shared.IsDevelopmentEnv(h.env),
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+		Expires:  time.Now().Add(7 * 24 * time.Hour),
+	})
+}
+
+func (h *apiHandler) clearRefreshTokenCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refreshToken",
+		Value:    "",
+		Path:     csrfCookiePath,
+		HttpOnly: true,
+		Secure:   !shared.IsDevelopmentEnv(h.env),
+		SameSite: http.SameSiteNoneMode,
+		MaxAge:   -1,
+	})
+}
+
+// userETag derives the entity tag of a profile from its stored version and
+// last login, the only field that changes without bumping the version.
+func userETag(user *domain.User) string {
+	version := user.Id + "@" + strconv.FormatInt(user.UpdatedAt.UnixNano(), 10)
+	if user.LastLoginAt != nil {
+		version += "@" + strconv.FormatInt(user.LastLoginAt.UnixNano(), 10)
+	}
+	return versionETag(version)
+}
+
+func toUserProfile(user *domain.User) *rest.UserProfile {
+	roles := user.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	return &rest.UserProfile{
+		Id:               user.Id,
+		Email:            user.Email,
+		DisplayName:      user.DisplayName,
+		AvatarURL:        user.AvatarURL,
+		Locale:           user.Locale,
+		Timezone:         user.Timezone,
+		EmailVerified:    user.EmailVerified,
+		TwoFactorEnabled: user.TwoFactorEnabled,
+		Roles:            roles,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
+		LastLoginAt:      user.LastLoginAt,
+	}
+}
@@ -0,0 +1,168 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"go-web/internal/core/models"
+	"go-web/internal/platform"
+)
+
+const (
+	idempotencyHeader    = "Idempotency-Key"
+	idempotencyMaxKeyLen = 255
+	idempotencyTTL       = 60 * 60 * 24 // 24 hours
+	idempotencyLockTTL   = 60           // 1 minute
+)
+
+// idempotencyRecord is what is cached under an idempotency key. A record
+// without a status marks a request that is still being processed.
+type idempotencyRecord struct {
+	Fingerprint string
+	Status      int
+	Header      http.Header
+	Body        []byte
+}
+
+// idempotent makes retries of an unsafe endpoint safe. The first response for
+// an Idempotency-Key is stored and replayed to later requests with the same
+// key, user and route. A duplicate arriving while the first one is still in
+// flight gets a 409 and a key reused with a different payload gets a 422.
+// Anonymous requests are told apart by their payload instead of their user, so
+// that unrelated clients picking the same key never share a response.
+func (h *apiHandler) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyHeader)
+		if key == "" || h.cache == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > idempotencyMaxKeyLen {
+			respondError(w, models.InvalidParam("Idempotency-Key is too long", nil))
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+		if err != nil {
+			respondError(w, models.InvalidBody("Invalid request body", err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.Sum256(body)
+		fingerprint := hex.EncodeToString(sum[:])
+		user := requestUser(r)
+		if user == "" {
+			user = "anonymous:" + fingerprint
+		}
+		cacheKey := idempotencyCacheKey(key, user, r.Method+" "+r.URL.Path)
+
+		added, err := h.cache.Add(cacheKey, &idempotencyRecord{Fingerprint: fingerprint}, idempotencyLockTTL)
+		if err != nil {
+			respondError(w, models.Internal(err))
+			return
+		}
+		if !added {
+			h.replay(w, cacheKey, fingerprint)
+			return
+		}
+
+		// Server errors and panics are not stored so that the client can
+		// retry them.
+		stored := false
+		defer func() {
+			if stored {
+				return
+			}
+			if err := h.cache.Delete(cacheKey); err != nil {
+				slog.Error("failed to release idempotency key", "error", err.Error())
+			}
+		}()
+		rw := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+		if rw.status >= http.StatusInternalServerError {
+			return
+		}
+		stored = true
+		record := &idempotencyRecord{
+			Fingerprint: fingerprint,
+			Status:      rw.status,
+			Header:      rw.header,
+			Body:        rw.body.Bytes(),
+		}
+		if err := h.cache.SetWithTTL(cacheKey, record, idempotencyTTL); err != nil {
+			slog.Error("failed to store idempotent response", "error", err.Error())
+		}
+	})
+}
+
+func (h *apiHandler) replay(w http.ResponseWriter, cacheKey, fingerprint string) {
+	var record idempotencyRecord
+	if err := h.cache.Get(cacheKey, &record); err != nil {
+		// The first request finished with a server error between our calls.
+		respondError(w, models.Conflict("A request with this Idempotency-Key is in progress", err))
+		return
+	}
+	if record.Fingerprint != fingerprint {
+		respondError(w, models.Unprocessable("Idempotency-Key was already used with a different payload", nil))
+		return
+	}
+	if record.Status == 0 {
+		respondError(w, models.Conflict("A request with this Idempotency-Key is in progress", nil))
+		return
+	}
+	for k, v := range record.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.Header().Set("Content-Length", strconv.Itoa(len(record.Body)))
+	w.WriteHeader(record.Status)
+	//nolint:errcheck
+	w.Write(record.Body)
+}
+
+func idempotencyCacheKey(key, user, route string) string {
+	sum := sha256.Sum256([]byte(key + "\x00" + user + "\x00" + route))
+	return "idempotency:" + hex.EncodeToString(sum[:])
+}
+
+// requestUser returns the authenticated user id, or "" for anonymous requests.
+func requestUser(r *http.Request) string {
+	claims, ok := r.Context().Value(platform.CtxUserKey).(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	sub, _ := claims["sub"].(string)
+	return sub
+}
+
+// recordingWriter passes the response through while keeping a copy of it.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+	wrote  bool
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+	w.status = code
+	w.header = w.Header().Clone()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
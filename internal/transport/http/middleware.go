@@ -80,6 +80,39 @@ func (h *apiHandler) authorize(next http.Handler) http.Handler {
 	})
 }
 
+// requireRole lets through the requests of active users holding role. The
+// account is checked on every request instead of trusting the roles of the
+// access token, so that disabling it, revoking its sessions or removing the
+// role applies at once. It must run after authorize.
+func (h *apiHandler) requireRole(role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := r.Context().Value(platform.CtxUserKey).(map[string]interface{})
+		userId, _ := claims["sub"].(string)
+		gen, _ := claims["gen"].(string)
+		if err := h.auth.CheckAccess(r.Context(), userId, gen, role); err != nil {
+			respondError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *apiHandler) authorizeAdmin(next http.HandlerFunc) http.Handler {
+	return h.authorize(h.requireRole(models.RoleAdmin, next))
+}
+
+// requireFeature rejects the requests while the feature toggle is off. The
+// toggle is read on every request, so that config reloads apply at once.
+func (h *apiHandler) requireFeature(name string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.features != nil && !h.features(name) {
+			respondError(w, models.Forbidden("This feature is disabled", nil))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func HttpMetricMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
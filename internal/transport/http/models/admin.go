@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+type AdminUser struct {
+	UserProfile
+	Status     string     `json:"status" enums:"active,disabled,deleted"`
+	DisabledAt *time.Time `json:"disabledAt"`
+	DeletedAt  *time.Time `json:"deletedAt"`
+}
+
+type AdminUserResponseBody struct {
+	Data       *AdminUser `json:"data"`
+	StatusCode int        `json:"statusCode"`
+}
+
+type AdminLogoutResponseBody struct {
+	Data       *string `json:"data"`
+	StatusCode int     `json:"statusCode"`
+}
+
+// ListUsersResponseBody names the envelope of the user list for the api docs,
+// which cannot refer to an instantiated generic type.
+type ListUsersResponseBody = ListResponseBody[AdminUser]
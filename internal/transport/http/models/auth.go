@@ -6,8 +6,9 @@ type LoginRequestBody struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
-	Type  string `json:"type"`
+	Token     string `json:"token"`
+	Type      string `json:"type"`
+	CsrfToken string `json:"csrfToken"`
 }
 
 type LoginResponseBody struct {
@@ -17,7 +18,7 @@ type LoginResponseBody struct {
 
 type RegisterRequestBody struct {
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=3"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
 }
 
 type RegisterResponse struct {
@@ -30,11 +31,6 @@ type RegisterResponseBody struct {
 	StatusCode int               `json:"statusCode"`
 }
 
-type GetMeResponseBody struct {
-	Data       string `json:"data"`
-	StatusCode int    `json:"statusCode"`
-}
-
 type RefreshTokenResponseBody struct {
 	Data       *LoginResponse `json:"data"`
 	StatusCode int            `json:"statusCode"`
@@ -0,0 +1,11 @@
+package models
+
+// ListResponseBody is the envelope of every list endpoint.
+type ListResponseBody[T any] struct {
+	Data []T `json:"data"`
+	// NextCursor is passed as the cursor parameter, with the same filters and
+	// sort, to read the next page. It is null on the last page.
+	NextCursor *string `json:"nextCursor"`
+	HasMore    bool    `json:"hasMore"`
+	StatusCode int     `json:"statusCode"`
+}
@@ -0,0 +1,97 @@
+package models
+
+import "time"
+
+type UserProfile struct {
+	Id               string     `json:"id"`
+	Email            string     `json:"email"`
+	DisplayName      string     `json:"displayName"`
+	AvatarURL        string     `json:"avatarUrl"`
+	Locale           string     `json:"locale"`
+	Timezone         string     `json:"timezone"`
+	EmailVerified    bool       `json:"emailVerified"`
+	TwoFactorEnabled bool       `json:"twoFactorEnabled"`
+	Roles            []string   `json:"roles"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+	LastLoginAt      *time.Time `json:"lastLoginAt"`
+}
+
+type GetMeResponseBody struct {
+	Data       *UserProfile `json:"data"`
+	StatusCode int          `json:"statusCode"`
+}
+
+type UpdateMeRequestBody struct {
+	DisplayName *string `json:"displayName" validate:"omitempty,max=64"`
+	AvatarURL   *string `json:"avatarUrl" validate:"omitempty,max=2048,http_url"`
+	Locale      *string `json:"locale" validate:"omitempty,bcp47_language_tag"`
+	Timezone    *string `json:"timezone" validate:"omitempty,timezone"`
+}
+
+type UpdateMeResponseBody struct {
+	Data       *UserProfile `json:"data"`
+	StatusCode int          `json:"statusCode"`
+}
+
+type ChangePasswordRequestBody struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required"`
+}
+
+type ChangePasswordResponseBody struct {
+	Data       *LoginResponse `json:"data"`
+	StatusCode int            `json:"statusCode"`
+}
+
+type ChangeEmailRequestBody struct {
+	Password string `json:"password" validate:"required"`
+	NewEmail string `json:"newEmail" validate:"required,email"`
+}
+
+type ChangeEmailResponseBody struct {
+	Data       *string `json:"data"`
+	StatusCode int     `json:"statusCode"`
+}
+
+type ConfirmEmailRequestBody struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ConfirmEmailResponseBody struct {
+	Data       *string `json:"data"`
+	StatusCode int     `json:"statusCode"`
+}
+
+type DeleteMeRequestBody struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type DeleteMeResponseBody struct {
+	Data       *string `json:"data"`
+	StatusCode int     `json:"statusCode"`
+}
+
+type SessionInfo struct {
+	Id        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type AuditEntryInfo struct {
+	Id        string    `json:"id"`
+	Action    string    `json:"action"`
+	Detail    string    `json:"detail"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type UserExport struct {
+	Profile      *UserProfile     `json:"profile"`
+	Sessions     []SessionInfo    `json:"sessions"`
+	AuditEntries []AuditEntryInfo `json:"auditEntries"`
+	ExportedAt   time.Time        `json:"exportedAt"`
+}
+
+type ExportMeResponseBody struct {
+	Data       *UserExport `json:"data"`
+	StatusCode int         `json:"statusCode"`
+}
@@ -0,0 +1,31 @@
+package http
+
+import (
+	"net/http"
+
+	"go-web/internal/platform"
+
+	"golang.org/x/net/http2"
+)
+
+func newHTTP2Server(cfg platform.HTTP2Config) *http2.Server {
+	return &http2.Server{
+		MaxConcurrentStreams: cfg.MaxConcurrentStreams,
+		MaxReadFrameSize:     cfg.MaxReadFrameSize,
+		IdleTimeout:          cfg.IdleTimeout,
+	}
+}
+
+// AltSvcMiddleware advertises alternative services, typically an HTTP/3
+// endpoint, to clients. An empty value disables it.
+func AltSvcMiddleware(value string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if value == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Alt-Svc", value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	domain "go-web/internal/core/models"
+	rest "go-web/internal/transport/http/models"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// queryParam is a filter accepted by a list endpoint.
+type queryParam struct {
+	field string
+	op    domain.FilterOp
+	// parse converts and validates the raw value. Values are kept as strings
+	// when it is nil.
+	parse func(string) (any, error)
+}
+
+// listSpec is the whitelist of what a list endpoint accepts besides limit,
+// cursor and sort.
+type listSpec struct {
+	filters map[string]queryParam
+	// sorts maps the sortable fields to the parser of their cursor values.
+	sorts map[string]func(string) (any, error)
+	// defaultSort is a sortable field, prefixed with - for descending order.
+	defaultSort string
+}
+
+// parseListQuery reads the limit, cursor, sort and filters of a list
+// endpoint. Parameters the spec does not know are rejected.
+func parseListQuery(r *http.Request, spec listSpec) (*domain.ListQuery, error) {
+	params := r.URL.Query()
+	query := &domain.ListQuery{Limit: defaultPageSize}
+	for name := range params {
+		if name == "limit" || name == "cursor" || name == "sort" {
+			continue
+		}
+		param, ok := spec.filters[name]
+		if !ok {
+			return nil, domain.InvalidParam("Unknown query parameter "+name, nil)
+		}
+		var value any = params.Get(name)
+		if param.parse != nil {
+			v, err := param.parse(params.Get(name))
+			if err != nil {
+				return nil, domain.InvalidParam("Invalid "+name+": "+err.Error(), err)
+			}
+			value = v
+		}
+		query.Filters = append(query.Filters, domain.Filter{Field: param.field, Op: param.op, Value: value})
+	}
+	// Map iteration is random; a stable order keeps the generated SQL stable.
+	slices.SortFunc(query.Filters, func(a, b domain.Filter) int {
+		return strings.Compare(a.Field+string(a.Op), b.Field+string(b.Op))
+	})
+
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return nil, domain.InvalidParam("limit must be between 1 and "+strconv.Itoa(maxPageSize), err)
+		}
+		query.Limit = limit
+	}
+
+	sort := params.Get("sort")
+	if sort == "" {
+		sort = spec.defaultSort
+	}
+	query.Desc = strings.HasPrefix(sort, "-")
+	query.Sort = strings.TrimPrefix(sort, "-")
+	parseCursor, ok := spec.sorts[query.Sort]
+	if !ok {
+		fields := make([]string, 0, len(spec.sorts))
+		for f := range spec.sorts {
+			fields = append(fields, f)
+		}
+		slices.Sort(fields)
+		return nil, domain.InvalidParam("sort must be one of "+strings.Join(fields, ", "), nil)
+	}
+
+	if v := params.Get("cursor"); v != "" {
+		after, err := decodeCursor(v, query.Sort, query.Desc)
+		if err != nil {
+			return nil, err
+		}
+		if parseCursor != nil {
+			if _, err := parseCursor(after.Value); err != nil {
+				return nil, domain.InvalidParam("Invalid cursor", err)
+			}
+		}
+		query.After = after
+	}
+	return query, nil
+}
+
+// parseTimeParam parses an RFC 3339 time.
+func parseTimeParam(v string) (any, error) {
+	return time.Parse(time.RFC3339, v)
+}
+
+func parseCursorTime(v string) (any, error) {
+	return time.Parse(time.RFC3339Nano, v)
+}
+
+// oneOf accepts the listed values only.
+func oneOf(values ...string) func(string) (any, error) {
+	return func(v string) (any, error) {
+		if !slices.Contains(values, v) {
+			return nil, errors.New("must be one of " + strings.Join(values, ", "))
+		}
+		return v, nil
+	}
+}
+
+// pageCursor is the opaque cursor handed to clients. It remembers the sort it
+// was issued for so that it is not applied to another order.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v"`
+	Id    string `json:"i"`
+}
+
+func encodeCursor(query *domain.ListQuery, next *domain.Cursor) string {
+	//nolint:errcheck
+	b, _ := json.Marshal(pageCursor{Sort: query.Sort, Desc: query.Desc, Value: next.Value, Id: next.Id})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s, sort string, desc bool) (*domain.Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, domain.InvalidParam("Invalid cursor", err)
+	}
+	var c pageCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Id == "" {
+		return nil, domain.InvalidParam("Invalid cursor", err)
+	}
+	if c.Sort != sort || c.Desc != desc {
+		return nil, domain.InvalidParam("Cursor was issued for another sort", nil)
+	}
+	return &domain.Cursor{Value: c.Value, Id: c.Id}, nil
+}
+
+// newListResponse wraps a page in the envelope shared by every list endpoint.
+func newListResponse[T, D any](query *domain.ListQuery, page *domain.Page[T], convert func(*T) D) *rest.ListResponseBody[D] {
+	resp := &rest.ListResponseBody[D]{Data: make([]D, 0, len(page.Items)), StatusCode: http.StatusOK}
+	for i := range page.Items {
+		resp.Data = append(resp.Data, convert(&page.Items[i]))
+	}
+	if page.Next != nil {
+		cursor := encodeCursor(query, page.Next)
+		resp.NextCursor = &cursor
+		resp.HasMore = true
+	}
+	return resp
+}
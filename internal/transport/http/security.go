@@ -0,0 +1,66 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-web/internal/platform"
+)
+
+// SecurityHeadersMiddleware sets the configured security headers before the
+// response is written. Swagger UI under /docs/ gets the relaxed Docs profile
+// because it relies on inline scripts and styles.
+func SecurityHeadersMiddleware(cfg platform.SecurityHeadersConfig) func(next http.Handler) http.Handler {
+	hsts := hstsValue(cfg.HSTS)
+	return func(next http.Handler) http.Handler {
+		if !cfg.Enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			if hsts != "" && isHttps(r, cfg.HSTS.TrustForwardedProto) {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+			if cfg.NoSniff {
+				h.Set("X-Content-Type-Options", "nosniff")
+			}
+			setHeader(h, "Referrer-Policy", cfg.ReferrerPolicy)
+			setHeader(h, "Permissions-Policy", cfg.PermissionsPolicy)
+			profile := cfg.Api
+			if strings.HasPrefix(r.URL.Path, "/docs/") {
+				profile = cfg.Docs
+			}
+			setHeader(h, "X-Frame-Options", profile.FrameOptions)
+			setHeader(h, "Content-Security-Policy", profile.ContentSecurityPolicy)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func hstsValue(cfg platform.HSTSConfig) string {
+	if !cfg.Enabled || cfg.MaxAge <= 0 {
+		return ""
+	}
+	v := fmt.Sprintf("max-age=%d", int64(cfg.MaxAge.Seconds()))
+	if cfg.IncludeSubdomains {
+		v += "; includeSubDomains"
+	}
+	if cfg.Preload {
+		v += "; preload"
+	}
+	return v
+}
+
+func isHttps(r *http.Request, trustForwardedProto bool) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return trustForwardedProto && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+func setHeader(h http.Header, key, val string) {
+	if val != "" {
+		h.Set(key, val)
+	}
+}
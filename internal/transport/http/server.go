@@ -2,24 +2,35 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"log/slog"
 	"net/http"
 	"time"
 
+	domain "go-web/internal/core/models"
 	"go-web/internal/core/ports"
 	"go-web/internal/core/service"
 	"go-web/internal/infra/cache"
 	"go-web/internal/infra/hasher"
 	"go-web/internal/infra/limiter"
+	"go-web/internal/infra/mailer"
+	"go-web/internal/infra/publisher"
+	"go-web/internal/infra/secret"
 	"go-web/internal/infra/store"
 	"go-web/internal/infra/token"
 	"go-web/internal/infra/validator"
 	"go-web/internal/platform"
 
-	"github.com/rs/cors"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"golang.org/x/time/rate"
 )
 
-var server *http.Server
+var (
+	server         *http.Server
+	stopBackground context.CancelFunc
+)
 
 func newServer(opts ...func(*http.Server)) *http.Server {
 	s := &http.Server{}
@@ -49,44 +60,264 @@ func withHandler(h http.Handler) func(*http.Server) {
 	}
 }
 
-func RunServer(cfg *platform.Config) error {
+func withTLS(cfg *tls.Config) func(*http.Server) {
+	return func(s *http.Server) {
+		s.TLSConfig = cfg
+	}
+}
+
+func withIdleTimeout(d time.Duration) func(*http.Server) {
+	return func(s *http.Server) {
+		s.IdleTimeout = d
+	}
+}
+
+func withMaxHeaderBytes(n int) func(*http.Server) {
+	return func(s *http.Server) {
+		s.MaxHeaderBytes = n
+	}
+}
+
+// WithHTTP2 must be applied after withHandler and withTLS, since it configures
+// the TLS settings and wraps the handler for h2c. It is exported so that test
+// servers speak HTTP/2 exactly as RunServer does.
+func WithHTTP2(cfg platform.HTTP2Config) func(*http.Server) {
+	return func(s *http.Server) {
+		h2s := newHTTP2Server(cfg)
+		if s.TLSConfig != nil {
+			if err := http2.ConfigureServer(s, h2s); err != nil {
+				slog.Error("failed to configure http2", "error", err.Error())
+			}
+		}
+		if cfg.H2C {
+			s.Handler = h2c.NewHandler(s.Handler, h2s)
+		}
+	}
+}
+
+func newSecretProvider(cfg *platform.Config) ports.SecretProvider {
+	switch cfg.Secrets.Provider {
+	case "file":
+		return secret.NewFileProvider(cfg.Secrets.Dir)
+	case "vault":
+		v := cfg.Secrets.Vault
+		return secret.NewVaultProvider(v.Addr, v.Token, v.Mount, v.Path)
+	default:
+		return secret.NewEnvProvider()
+	}
+}
+
+// newStore opens the store selected by cfg.Store.Driver.
+func newStore(ctx context.Context, cfg *platform.Config, secrets *secret.Refresher) (ports.Store, error) {
+	switch cfg.Store.Driver {
+	case "memory":
+		slog.Warn("using the memory store, data is lost on restart")
+		return store.NewMemStore(), nil
+	case "sqlite":
+		return store.NewSQLiteStore(cfg.Store.Path)
+	}
+	isolation, err := store.IsolationLevel(cfg.Store.TxIsolation)
+	if err != nil {
+		slog.Warn("using the default isolation level", "error", err.Error())
+	}
+	var replicas []store.PgReplica
+	for _, addr := range cfg.Store.Replicas {
+		replicas = append(replicas, store.PgReplica{Name: addr, DSN: func() string {
+			return cfg.ReplicaAddrWithPassword(addr, secrets.Get(secret.StorePassword))
+		}})
+	}
+	s, err := store.NewPgStoreWithDSN(ctx, func() string {
+		return cfg.StoreAddrWithPassword(secrets.Get(secret.StorePassword))
+	}, store.PgOptions{
+		MaxOpenConns:    cfg.Store.MaxOpenConns,
+		MaxIdleConns:    cfg.Store.MaxIdleConns,
+		ConnMaxLifetime: cfg.Store.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Store.ConnMaxIdleTime,
+		ConnectTimeout:  cfg.Store.ConnectTimeout,
+		Tx:              store.TxOptions{Isolation: isolation, MaxRetries: cfg.Store.TxMaxRetries},
+
+		Replicas:             replicas,
+		ReplicaCheckInterval: cfg.Store.ReplicaCheckInterval,
+		SlowQueryThreshold:   cfg.Store.SlowQueryThreshold,
+		Metrics:              prometheus.DefaultRegisterer,
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("connected to db server", "host", cfg.Store.Host, "port", cfg.Store.Port, "replicas", len(replicas))
+	return s, nil
+}
+
+func newCache(cfg *platform.Config) ports.Cache {
+	if cfg.Cache.Driver == "memory" {
+		return cache.NewLocalCache()
+	}
+	slog.Info("connected to cache server on", "addr", cfg.CacheAddr())
+	return cache.NewMemCache(cfg.CacheAddr())
+}
+
+func newPublisher(cfg *platform.Config, secrets *secret.Refresher) ports.Publisher {
+	if cfg.Events.Publisher == "webhook" {
+		slog.Info("publishing events to a webhook", "url", cfg.Events.WebhookURL)
+		return publisher.NewWebhookPublisher(cfg.Events.WebhookURL, func() string {
+			return secrets.Get(secret.WebhookSecret)
+		}, cfg.Events.WebhookTimeout)
+	}
+	return publisher.NewLogPublisher()
+}
+
+// runEventRelay publishes the events of the outbox on every relay interval
+// and removes those published more than the retention ago once an hour,
+// until ctx is done.
+func runEventRelay(ctx context.Context, events ports.EventService, cfg platform.EventsConfig) {
+	relay := time.NewTicker(cfg.RelayInterval)
+	defer relay.Stop()
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-relay.C:
+			if _, err := events.RelayEvents(ctx); err != nil {
+				slog.Error("failed to relay events", "error", err.Error())
+			}
+		case <-purge.C:
+			n, err := events.PurgePublished(ctx, cfg.Retention)
+			if err != nil {
+				slog.Error("failed to purge published events", "error", err.Error())
+				continue
+			}
+			if n > 0 {
+				slog.Info("purged published events", "count", n)
+			}
+		}
+	}
+}
+
+// runAccountPurge removes the deleted accounts whose grace period is over on
+// every purge interval until ctx is done.
+func runAccountPurge(ctx context.Context, users ports.UserService, cfg platform.AccountConfig) {
+	ticker := time.NewTicker(cfg.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := users.PurgeDeleted(ctx, cfg.DeletionGracePeriod)
+			if err != nil {
+				slog.Error("failed to purge deleted accounts", "error", err.Error())
+				continue
+			}
+			if n > 0 {
+				slog.Info("purged deleted accounts", "count", n)
+			}
+		}
+	}
+}
+
+// grantAdmins gives the admin role to the accounts of emails. Failures are only
+// logged, an account may simply not be registered yet.
+func grantAdmins(ctx context.Context, admin ports.AdminService, emails []string) {
+	for _, email := range emails {
+		if _, err := admin.GrantAdmin(ctx, "config", email); err != nil {
+			slog.Warn("failed to grant the admin role", "email", email, "error", err.Error())
+		}
+	}
+}
+
+func RunServer(watcher *platform.ConfigWatcher) error {
+	cfg := watcher.Config()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopBackground = cancel
+
+	secrets := secret.NewRefresher(newSecretProvider(cfg), cfg.Secrets.RefreshInterval, map[string]string{
+		secret.JwtSecret:     cfg.Auth.JwtSecret,
+		secret.StorePassword: cfg.Store.Password,
+		secret.WebhookSecret: cfg.Events.WebhookSecret,
+	})
+	if err := secrets.Load(ctx); err != nil {
+		return err
+	}
+	if err := cfg.ValidateSecrets(secrets.Get(secret.JwtSecret), secrets.Get(secret.StorePassword), secrets.Get(secret.WebhookSecret)); err != nil {
+		return err
+	}
+	go secrets.Run(ctx)
+
+	s, err := newStore(ctx, cfg, secrets)
+	if err != nil {
+		return err
+	}
+
 	mux := http.NewServeMux()
 	api := newApiHandler(func(a *apiHandler) {
-		var s ports.Store
 		var c ports.Cache
 		var h ports.Hasher
 		var t ports.TokenGenerator
 		var l ports.RateLimiter
 
-		s = store.NewPgStore(cfg.StoreAddr())
-		if s != nil {
-			slog.Info("connected to db server on", "addr", cfg.StoreAddr())
-		}
-		if cfg.CacheEnabled {
-			c = cache.NewMemCache(cfg.CacheAddr())
-			slog.Info("connected to cache server on", "addr", cfg.CacheAddr())
+		if cfg.Cache.Enabled {
+			c = newCache(cfg)
 		}
 		h = hasher.NewBcryptHasher()
-		t = token.NewJwtGenerator(cfg.JwtSecret, time.Minute*5)
-		l = limiter.NewMemLimiter(100000, 300000)
+		t = token.NewRotatingJwtGenerator(func() string {
+			return secrets.Get(secret.JwtSecret)
+		}, cfg.Auth.AccessTokenTTL)
+		l = limiter.NewMemLimiter(rate.Limit(cfg.Limiter.Rate), cfg.Limiter.Burst)
 
-		a.auth = service.NewAuthService(s, c, h, t)
+		a.auth = service.NewAuthService(s, c, h, t, mailer.NewLogMailer(), domain.EmailNormalizer{LowercaseLocal: cfg.Account.LowercaseEmails})
+		a.users = service.NewUserService(s, c)
+		a.admin = service.NewAdminService(s, c)
 		a.validator = validator.NewValidator()
 		a.cache = c
 		a.limiter = l
 		a.env = cfg.Env
+		a.csrf = cfg.Csrf
+		a.features = watcher.FeatureEnabled
 	})
 	api.RegisterRoutes(mux)
-	handler := RegisterMiddlewares(mux, api.RateLimitMiddleware, HttpMetricMiddleware)
-	server = newServer(
-		withAddr(cfg.HttpServerAddr()),
-		withHandler(cors.Default().Handler(handler)),
-		withTimeouts(5*time.Second, 10*time.Second, 2*time.Second),
+	handler := RegisterMiddlewares(
+		mux,
+		ClientCertMiddleware,
+		SecurityHeadersMiddleware(cfg.SecurityHeaders),
+		CompressionMiddleware(cfg.Compression),
+		ETagMiddleware,
+		api.RateLimitMiddleware,
+		HttpMetricMiddleware,
 	)
-	return server.ListenAndServe()
+	grantAdmins(ctx, api.admin, cfg.Auth.AdminEmails)
+	go runAccountPurge(ctx, api.users, cfg.Account)
+	go runEventRelay(ctx, service.NewEventService(s, newPublisher(cfg, secrets)), cfg.Events)
+	watcher.OnReload(func(old, new *platform.Config) {
+		if old.Limiter != new.Limiter {
+			api.limiter.SetLimit(new.Limiter.Rate, new.Limiter.Burst)
+		}
+	})
+	opts := []func(*http.Server){
+		withAddr(cfg.HttpServerAddr()),
+		withHandler(AltSvcMiddleware(cfg.Http.HTTP2.AltSvc)(CorsHandler(watcher, handler))),
+		withTimeouts(cfg.Http.ReadTimeout, cfg.Http.WriteTimeout, cfg.Http.ReadHeaderTimeout),
+		withIdleTimeout(cfg.Http.IdleTimeout),
+		withMaxHeaderBytes(cfg.Http.MaxHeaderBytes),
+	}
+	if !cfg.Http.TLS.Enabled {
+		server = newServer(append(opts, WithHTTP2(cfg.Http.HTTP2))...)
+		return server.ListenAndServe()
+	}
+
+	tlsCfg, err := NewTLSConfig(ctx, cfg.Http.TLS)
+	if err != nil {
+		return err
+	}
+	server = newServer(append(opts, withTLS(tlsCfg), WithHTTP2(cfg.Http.HTTP2))...)
+	return server.ListenAndServeTLS("", "")
 }
 
 func StopServer() error {
+	if stopBackground != nil {
+		stopBackground()
+	}
 	if server == nil {
 		return nil
 	}
@@ -0,0 +1,169 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"go-web/internal/platform"
+)
+
+// certReloader serves the certificate through GetCertificate and reloads it
+// whenever the certificate or key file changes on disk.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	cert    atomic.Pointer[tls.Certificate]
+	modTime time.Time
+}
+
+func newCertReloader(certFile, keyFile string, interval time.Duration) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, interval: interval}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load tls certificate: %w", err)
+	}
+	r.cert.Store(&cert)
+	r.modTime = r.latestModTime()
+	return nil
+}
+
+func (r *certReloader) latestModTime() time.Time {
+	var latest time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		if info, err := os.Stat(f); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// Run checks the files on every interval until ctx is done. A certificate that
+// fails to load is reported and the previous one keeps being served.
+func (r *certReloader) Run(ctx context.Context) {
+	if r.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.latestModTime().After(r.modTime) {
+				continue
+			}
+			if err := r.load(); err != nil {
+				slog.Error("failed to reload tls certificate", "error", err.Error())
+				continue
+			}
+			slog.Info("tls certificate reloaded", "cert", r.certFile)
+		}
+	}
+}
+
+// NewTLSConfig builds the TLS settings of cfg. The certificate is reloaded
+// from disk whenever its files change, until ctx is done.
+func NewTLSConfig(ctx context.Context, cfg platform.TLSConfig) (*tls.Config, error) {
+	certs, err := newCertReloader(cfg.CertFile, cfg.KeyFile, cfg.ReloadInterval)
+	if err != nil {
+		return nil, err
+	}
+	tlsCfg, err := newTLSConfig(cfg, certs)
+	if err != nil {
+		return nil, err
+	}
+	go certs.Run(ctx)
+	return tlsCfg, nil
+}
+
+func newTLSConfig(cfg platform.TLSConfig, certs *certReloader) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+	}
+	if cfg.MinVersion == "1.3" {
+		tlsCfg.MinVersion = tls.VersionTLS13
+	}
+	for _, name := range cfg.CipherSuites {
+		tlsCfg.CipherSuites = append(tlsCfg.CipherSuites, platform.CipherSuiteID(name))
+	}
+	switch cfg.ClientAuth {
+	case "request":
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return tlsCfg, nil
+	}
+	pem, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read client ca bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("client ca bundle contains no certificates")
+	}
+	tlsCfg.ClientCAs = pool
+	return tlsCfg, nil
+}
+
+// ClientIdentity describes the verified client certificate of a mutual TLS
+// connection.
+type ClientIdentity struct {
+	CommonName   string
+	Organization []string
+	DNSNames     []string
+	URIs         []string
+	SerialNumber string
+}
+
+// ClientIdentityFrom returns the identity stored by ClientCertMiddleware.
+func ClientIdentityFrom(ctx context.Context) (*ClientIdentity, bool) {
+	id, ok := ctx.Value(platform.CtxClientKey).(*ClientIdentity)
+	return id, ok
+}
+
+// ClientCertMiddleware exposes the verified client certificate, if any, to the
+// handlers through the request context.
+func ClientCertMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cert := r.TLS.VerifiedChains[0][0]
+		id := &ClientIdentity{
+			CommonName:   cert.Subject.CommonName,
+			Organization: cert.Subject.Organization,
+			DNSNames:     cert.DNSNames,
+			SerialNumber: cert.SerialNumber.String(),
+		}
+		for _, u := range cert.URIs {
+			id.URIs = append(id.URIs, u.String())
+		}
+		ctx := context.WithValue(r.Context(), platform.CtxClientKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -5,11 +5,21 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"sync/atomic"
 
 	domain "go-web/internal/core/models"
 	rest "go-web/internal/transport/http/models"
 )
 
+// maxRequestBody caps the request bodies the handlers read.
+const maxRequestBody = 1 << 20 // 1MiB
+
+// decodeBody decodes the JSON body of r into v, reading at most maxRequestBody
+// bytes of it.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) error {
+	return json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(v)
+}
+
 func respondSuccess(w http.ResponseWriter, code int, resp any) {
 	writeJson(w, code, resp)
 }
@@ -51,18 +61,45 @@ func (w *statusWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+// swapHandler lets the wrapped handler be replaced while serving requests.
+type swapHandler struct {
+	handler atomic.Pointer[http.Handler]
+}
+
+func newSwapHandler(h http.Handler) *swapHandler {
+	s := &swapHandler{}
+	s.Swap(h)
+	return s
+}
+
+func (s *swapHandler) Swap(h http.Handler) {
+	s.handler.Store(&h)
+}
+
+func (s *swapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*s.handler.Load()).ServeHTTP(w, r)
+}
+
 func mapAppErrorTypeToStatusCode(typ domain.ErrorType) int {
 	switch typ {
 	case domain.ErrInvalidParam, domain.ErrInvalidBody:
 		return http.StatusBadRequest
 	case domain.ErrInvalidAccess:
 		return http.StatusUnauthorized
+	case domain.ErrForbidden:
+		return http.StatusForbidden
 	case domain.ErrConflict:
 		return http.StatusConflict
 	case domain.ErrNotFound:
 		return http.StatusNotFound
+	case domain.ErrUnprocessable:
+		return http.StatusUnprocessableEntity
+	case domain.ErrPrecondition:
+		return http.StatusPreconditionFailed
 	case domain.ErrTooManyReq:
 		return http.StatusTooManyRequests
+	case domain.ErrUnsupported:
+		return http.StatusUnsupportedMediaType
 	default:
 		return http.StatusInternalServerError
 	}
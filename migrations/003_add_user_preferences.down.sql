@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_users_last_login_at;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS avatar_url,
+    DROP COLUMN IF EXISTS locale,
+    DROP COLUMN IF EXISTS timezone,
+    DROP COLUMN IF EXISTS last_login_at;
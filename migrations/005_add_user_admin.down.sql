@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_roles;
+DROP INDEX IF EXISTS idx_users_email_pattern;
+DROP INDEX IF EXISTS idx_users_created_at_id;
+
+ALTER TABLE users DROP COLUMN IF EXISTS disabled_at;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_users_email_lower_pattern;
+CREATE INDEX IF NOT EXISTS idx_users_email_pattern ON users (email text_pattern_ops);
+
+DROP INDEX IF EXISTS users_email_lower_key;
@@ -0,0 +1,33 @@
+-- Addresses differing only in case or surrounding spaces identify the same
+-- account from now on. Such accounts must be merged or removed by hand first;
+-- the migration lists them and stops.
+DO $$
+DECLARE
+    collisions TEXT;
+BEGIN
+    SELECT string_agg(format('%s: %s', address, ids), '; ')
+    INTO collisions
+    FROM (
+        SELECT lower(btrim(email)) AS address, string_agg(id, ', ' ORDER BY created_at, id) AS ids
+        FROM users
+        GROUP BY lower(btrim(email))
+        HAVING count(*) > 1
+    ) AS duplicates;
+
+    IF collisions IS NOT NULL THEN
+        RAISE EXCEPTION 'users share an email address ignoring case: %', collisions
+            USING HINT = 'Keep one account per address, then run the migration again.';
+    END IF;
+END $$;
+
+-- Same normalization as models.EmailNormalizer, except for the conversion of
+-- internationalized domains to punycode, which SQL cannot do.
+UPDATE users
+SET email = substring(btrim(email) FROM '^(.*)@') || '@' || lower(substring(btrim(email) FROM '@([^@]*)$'))
+WHERE btrim(email) LIKE '%@%'
+    AND email <> substring(btrim(email) FROM '^(.*)@') || '@' || lower(substring(btrim(email) FROM '@([^@]*)$'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (lower(email));
+
+DROP INDEX IF EXISTS idx_users_email_pattern;
+CREATE INDEX IF NOT EXISTS idx_users_email_lower_pattern ON users (lower(email) text_pattern_ops);
@@ -0,0 +1,100 @@
+package http_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"go-web/tests/utils"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportMe(t *testing.T) {
+	ts := utils.SetupTestServer()
+	defer ts.Server.Close()
+
+	email := "export" + utils.GenUserEmail()
+	ts.DoRequest(t, "POST", "/api/auth/register", map[string]string{"email": email, "password": "password123"}, "", nil, 201)
+	s := login(t, ts, email, "password123")
+
+	t.Run("json export", func(t *testing.T) {
+		var resp map[string]any
+		res := ts.DoRequest(t, "GET", "/api/me/export", nil, s.token, &resp, 200)
+		require.Equal(t, "no-store", res.Header.Get("Cache-Control"))
+		data := resp["data"].(map[string]any)
+		require.Equal(t, email, data["profile"].(map[string]any)["email"])
+		sessions := data["sessions"].([]any)
+		require.Len(t, sessions, 1)
+		require.NotContains(t, sessions[0].(map[string]any)["id"], s.cookies[0].Value, "refresh tokens must not be exported")
+		audit := data["auditEntries"].([]any)
+		require.Equal(t, "login", audit[0].(map[string]any)["action"])
+	})
+
+	t.Run("zip export", func(t *testing.T) {
+		req, err := http.NewRequest("GET", ts.Server.URL+"/api/me/export?format=zip", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+s.token)
+		res, err := ts.Client.Do(req)
+		require.NoError(t, err)
+		//nolint:errcheck
+		defer res.Body.Close()
+		require.Equal(t, 200, res.StatusCode)
+		require.Equal(t, "application/zip", res.Header.Get("Content-Type"))
+		require.Contains(t, res.Header.Get("Content-Disposition"), "attachment")
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		require.NoError(t, err)
+		names := map[string]bool{}
+		for _, f := range zr.File {
+			names[f.Name] = true
+			rc, err := f.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			require.True(t, json.Valid(content), f.Name)
+		}
+		require.Equal(t, map[string]bool{"profile.json": true, "sessions.json": true, "audit.json": true}, names)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		ts.DoRequest(t, "GET", "/api/me/export?format=xml", nil, s.token, nil, 400)
+	})
+}
+
+func TestDeleteMe(t *testing.T) {
+	ts := utils.SetupTestServer()
+	defer ts.Server.Close()
+
+	email := "delete" + utils.GenUserEmail()
+	ts.DoRequest(t, "POST", "/api/auth/register", map[string]string{"email": email, "password": "password123"}, "", nil, 201)
+	s := login(t, ts, email, "password123")
+	other := login(t, ts, email, "password123")
+
+	t.Run("wrong password is forbidden", func(t *testing.T) {
+		ts.DoRequest(t, "DELETE", "/api/me", map[string]string{"password": "nope"}, s.token, nil, 403)
+		ts.DoRequest(t, "GET", "/api/me", nil, s.token, nil, 200)
+	})
+
+	t.Run("password is required", func(t *testing.T) {
+		ts.DoRequest(t, "DELETE", "/api/me", map[string]string{}, s.token, nil, 400)
+	})
+
+	t.Run("deletion revokes every session", func(t *testing.T) {
+		ts.DoRequest(t, "DELETE", "/api/me", map[string]string{"password": "password123"}, s.token, nil, 200)
+		ts.DoRequest(t, "GET", "/api/me", nil, s.token, nil, 404)
+		refresh(t, ts, s, 401)
+		refresh(t, ts, other, 401)
+	})
+
+	t.Run("deleted account cannot log in or be registered again", func(t *testing.T) {
+		credentials := map[string]string{"email": email, "password": "password123"}
+		ts.DoRequest(t, "POST", "/api/auth/login", credentials, "", nil, 401)
+		ts.DoRequest(t, "POST", "/api/auth/register", credentials, "", nil, 409)
+	})
+}
@@ -0,0 +1,122 @@
+package http_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"go-web/tests/utils"
+
+	"github.com/stretchr/testify/require"
+)
+
+type session struct {
+	token     string
+	csrfToken string
+	cookies   []*http.Cookie
+}
+
+func login(t *testing.T, ts *utils.TestServer, email, password string) session {
+	var resp map[string]any
+	res := ts.DoRequest(t, "POST", "/api/auth/login", map[string]string{"email": email, "password": password}, "", &resp, 200)
+	data := resp["data"].(map[string]any)
+	return session{data["token"].(string), data["csrfToken"].(string), res.Cookies()}
+}
+
+func refresh(t *testing.T, ts *utils.TestServer, s session, wantStatus int) {
+	headers := map[string]string{"X-CSRF-Token": s.csrfToken}
+	ts.DoRequestWithHeaders(t, "POST", "/api/auth/refresh", nil, "", headers, nil, wantStatus, s.cookies...)
+}
+
+func TestChangePassword(t *testing.T) {
+	ts := utils.SetupTestServer()
+	defer ts.Server.Close()
+
+	email := "pw" + utils.GenUserEmail()
+	ts.DoRequest(t, "POST", "/api/auth/register", map[string]string{"email": email, "password": "password123"}, "", nil, 201)
+	current := login(t, ts, email, "password123")
+	other := login(t, ts, email, "password123")
+
+	t.Run("wrong current password is forbidden", func(t *testing.T) {
+		body := map[string]string{"currentPassword": "nope", "newPassword": "n3wPassword"}
+		var resp map[string]any
+		ts.DoRequest(t, "POST", "/api/me/password", body, current.token, &resp, 403)
+		require.Equal(t, "FORBIDDEN", resp["errorCode"])
+	})
+
+	t.Run("weak password is rejected", func(t *testing.T) {
+		body := map[string]string{"currentPassword": "password123", "newPassword": "weak"}
+		ts.DoRequest(t, "POST", "/api/me/password", body, current.token, nil, 400)
+	})
+
+	t.Run("password change revokes other sessions", func(t *testing.T) {
+		body := map[string]string{"currentPassword": "password123", "newPassword": "n3wPassword"}
+		var resp map[string]any
+		res := ts.DoRequest(t, "POST", "/api/me/password", body, current.token, &resp, 200)
+		data := resp["data"].(map[string]any)
+		require.NotEmpty(t, data["token"])
+
+		refresh(t, ts, other, 401)
+		refresh(t, ts, current, 401)
+		renewed := session{data["token"].(string), data["csrfToken"].(string), res.Cookies()}
+		refresh(t, ts, renewed, 200)
+	})
+
+	t.Run("only the new password logs in", func(t *testing.T) {
+		ts.DoRequest(t, "POST", "/api/auth/login", map[string]string{"email": email, "password": "password123"}, "", nil, 401)
+		login(t, ts, email, "n3wPassword")
+	})
+}
+
+func TestChangeEmail(t *testing.T) {
+	ts := utils.SetupTestServer()
+	defer ts.Server.Close()
+
+	oldEmail := "old" + utils.GenUserEmail()
+	newEmail := "new" + utils.GenUserEmail()
+	ts.DoRequest(t, "POST", "/api/auth/register", map[string]string{"email": oldEmail, "password": "password123"}, "", nil, 201)
+	s := login(t, ts, oldEmail, "password123")
+
+	t.Run("wrong password is forbidden", func(t *testing.T) {
+		body := map[string]string{"password": "nope", "newEmail": newEmail}
+		ts.DoRequest(t, "POST", "/api/me/email", body, s.token, nil, 403)
+		require.Empty(t, ts.Mailer.Sent(newEmail))
+	})
+
+	var token string
+	t.Run("request mails the new and the old address", func(t *testing.T) {
+		body := map[string]string{"password": "password123", "newEmail": newEmail}
+		ts.DoRequest(t, "POST", "/api/me/email", body, s.token, nil, 202)
+
+		confirm := ts.Mailer.Sent(newEmail)
+		require.Len(t, confirm, 1)
+		token = confirm[0].Body[strings.LastIndex(confirm[0].Body, " ")+1:]
+		require.NotEmpty(t, token)
+		require.Len(t, ts.Mailer.Sent(oldEmail), 1)
+
+		var resp map[string]any
+		ts.DoRequest(t, "GET", "/api/me", nil, s.token, &resp, 200)
+		require.Equal(t, oldEmail, resp["data"].(map[string]any)["email"], "email must not change before confirmation")
+	})
+
+	t.Run("invalid token is rejected", func(t *testing.T) {
+		ts.DoRequest(t, "POST", "/api/auth/email/confirm", map[string]string{"token": "bogus"}, "", nil, 400)
+	})
+
+	t.Run("confirmation switches the address", func(t *testing.T) {
+		ts.DoRequest(t, "POST", "/api/auth/email/confirm", map[string]string{"token": token}, "", nil, 200)
+		refresh(t, ts, s, 401)
+		ts.DoRequest(t, "POST", "/api/auth/login", map[string]string{"email": oldEmail, "password": "password123"}, "", nil, 401)
+
+		next := login(t, ts, newEmail, "password123")
+		var resp map[string]any
+		ts.DoRequest(t, "GET", "/api/me", nil, next.token, &resp, 200)
+		data := resp["data"].(map[string]any)
+		require.Equal(t, newEmail, data["email"])
+		require.Equal(t, true, data["emailVerified"])
+	})
+
+	t.Run("token cannot be reused", func(t *testing.T) {
+		ts.DoRequest(t, "POST", "/api/auth/email/confirm", map[string]string{"token": token}, "", nil, 400)
+	})
+}
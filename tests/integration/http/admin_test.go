@@ -0,0 +1,179 @@
+package http_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go-web/internal/core/models"
+	"go-web/internal/shared"
+	"go-web/tests/utils"
+
+	"github.com/stretchr/testify/require"
+)
+
+// registerAdmin registers a user, grants them the admin role and logs them in.
+func registerAdmin(t *testing.T, ts *utils.TestServer, email string) session {
+	ts.DoRequest(t, "POST", "/api/auth/register", map[string]string{"email": email, "password": "password123"}, "", nil, 201)
+	user, err := ts.Store.FindByEmail(context.Background(), email)
+	require.NoError(t, err)
+	require.NoError(t, ts.Store.UpdateRoles(context.Background(), user.Id, []string{"user", models.RoleAdmin}))
+	return login(t, ts, email, "password123")
+}
+
+func TestAdminUsers(t *testing.T) {
+	ts := utils.SetupTestServer()
+	defer ts.Server.Close()
+
+	prefix := "adm" + strings.ToLower(shared.RandString(8))
+	admin := registerAdmin(t, ts, prefix+"0@test.com")
+	ids := map[string]string{}
+	for _, name := range []string{"a", "b", "c"} {
+		var resp map[string]any
+		email := prefix + name + "@test.com"
+		ts.DoRequest(t, "POST", "/api/auth/register", map[string]string{"email": email, "password": "password123"}, "", &resp, 201)
+		ids[name] = resp["data"].(map[string]any)["id"].(string)
+	}
+	list := func(t *testing.T, query url.Values, wantStatus int) map[string]any {
+		var resp map[string]any
+		ts.DoRequest(t, "GET", "/api/admin/users?"+query.Encode(), nil, admin.token, &resp, wantStatus)
+		return resp
+	}
+	emails := func(resp map[string]any) []string {
+		var out []string
+		for _, u := range resp["data"].([]any) {
+			out = append(out, u.(map[string]any)["email"].(string))
+		}
+		return out
+	}
+
+	t.Run("admin role is required", func(t *testing.T) {
+		ts.DoRequest(t, "GET", "/api/admin/users", nil, "", nil, 401)
+		user := login(t, ts, prefix+"a@test.com", "password123")
+		var resp map[string]any
+		ts.DoRequest(t, "GET", "/api/admin/users", nil, user.token, &resp, 403)
+		require.Equal(t, "FORBIDDEN", resp["errorCode"])
+		ts.DoRequest(t, "POST", "/api/admin/users/"+ids["b"]+"/disable", nil, user.token, nil, 403)
+	})
+
+	t.Run("pages follow the cursor", func(t *testing.T) {
+		query := url.Values{"email": {prefix}, "role": {"user"}, "sort": {"email"}, "limit": {"3"}}
+		first := list(t, query, 200)
+		require.Equal(t, []string{prefix + "0@test.com", prefix + "a@test.com", prefix + "b@test.com"}, emails(first))
+		require.Equal(t, true, first["hasMore"])
+		require.NotNil(t, first["nextCursor"])
+
+		query.Set("cursor", first["nextCursor"].(string))
+		second := list(t, query, 200)
+		require.Equal(t, []string{prefix + "c@test.com"}, emails(second))
+		require.Equal(t, false, second["hasMore"])
+		require.Nil(t, second["nextCursor"])
+	})
+
+	t.Run("filters and descending sort", func(t *testing.T) {
+		resp := list(t, url.Values{"email": {prefix}, "role": {models.RoleAdmin}}, 200)
+		require.Equal(t, []string{prefix + "0@test.com"}, emails(resp))
+
+		resp = list(t, url.Values{"email": {prefix}, "sort": {"-email"}, "createdAfter": {"2000-01-01T00:00:00Z"}}, 200)
+		require.Equal(t, []string{prefix + "c@test.com", prefix + "b@test.com", prefix + "a@test.com", prefix + "0@test.com"}, emails(resp))
+
+		resp = list(t, url.Values{"email": {prefix}, "createdBefore": {"2000-01-01T00:00:00Z"}}, 200)
+		require.Empty(t, resp["data"])
+	})
+
+	t.Run("invalid query parameters", func(t *testing.T) {
+		list(t, url.Values{"limit": {"0"}}, 400)
+		list(t, url.Values{"sort": {"password_hash"}}, 400)
+		list(t, url.Values{"status": {"banned"}}, 400)
+		list(t, url.Values{"createdAfter": {"yesterday"}}, 400)
+		list(t, url.Values{"cursor": {"not a cursor"}}, 400)
+		list(t, url.Values{"password_hash": {"x"}}, 400)
+
+		first := list(t, url.Values{"email": {prefix}, "sort": {"email"}, "limit": {"1"}}, 200)
+		list(t, url.Values{"email": {prefix}, "sort": {"-email"}, "cursor": {first["nextCursor"].(string)}}, 400)
+	})
+
+	t.Run("get a user", func(t *testing.T) {
+		var resp map[string]any
+		ts.DoRequest(t, "GET", "/api/admin/users/"+ids["a"], nil, admin.token, &resp, 200)
+		data := resp["data"].(map[string]any)
+		require.Equal(t, prefix+"a@test.com", data["email"])
+		require.Equal(t, "active", data["status"])
+
+		ts.DoRequest(t, "GET", "/api/admin/users/missing", nil, admin.token, nil, 404)
+	})
+
+	t.Run("disable and enable", func(t *testing.T) {
+		email := prefix + "b@test.com"
+		user := login(t, ts, email, "password123")
+
+		var resp map[string]any
+		ts.DoRequest(t, "POST", "/api/admin/users/"+ids["b"]+"/disable", nil, admin.token, &resp, 200)
+		require.Equal(t, "disabled", resp["data"].(map[string]any)["status"])
+		require.NotNil(t, resp["data"].(map[string]any)["disabledAt"])
+
+		refresh(t, ts, user, 401)
+		ts.DoRequest(t, "POST", "/api/auth/login", map[string]string{"email": email, "password": "password123"}, "", nil, 403)
+		require.Equal(t, []string{email}, emails(list(t, url.Values{"email": {prefix}, "status": {"disabled"}}, 200)))
+
+		ts.DoRequest(t, "POST", "/api/admin/users/"+ids["b"]+"/enable", nil, admin.token, &resp, 200)
+		require.Equal(t, "active", resp["data"].(map[string]any)["status"])
+		login(t, ts, email, "password123")
+	})
+
+	t.Run("admins cannot disable themselves", func(t *testing.T) {
+		self, err := ts.Store.FindByEmail(context.Background(), prefix+"0@test.com")
+		require.NoError(t, err)
+		ts.DoRequest(t, "POST", "/api/admin/users/"+self.Id+"/disable", nil, admin.token, nil, 403)
+	})
+
+	t.Run("force logout", func(t *testing.T) {
+		user := login(t, ts, prefix+"c@test.com", "password123")
+		ts.DoRequest(t, "POST", "/api/admin/users/"+ids["c"]+"/logout", nil, admin.token, nil, 200)
+		refresh(t, ts, user, 401)
+		ts.DoRequest(t, "POST", "/api/admin/users/missing/logout", nil, admin.token, nil, 404)
+	})
+
+	t.Run("reset two-factor", func(t *testing.T) {
+		var resp map[string]any
+		ts.DoRequest(t, "POST", "/api/admin/users/"+ids["a"]+"/two-factor/reset", nil, admin.token, &resp, 200)
+		require.Equal(t, false, resp["data"].(map[string]any)["twoFactorEnabled"])
+	})
+}
+
+func TestAdminAccessRevocation(t *testing.T) {
+	ts := utils.SetupTestServer()
+	defer ts.Server.Close()
+
+	ctx := context.Background()
+	prefix := "rev" + strings.ToLower(shared.RandString(8))
+	admin := registerAdmin(t, ts, prefix+"0@test.com")
+	other := func(t *testing.T, name string) (session, string) {
+		s := registerAdmin(t, ts, prefix+name+"@test.com")
+		ts.DoRequest(t, "GET", "/api/admin/users", nil, s.token, nil, 200)
+		user, err := ts.Store.FindByEmail(ctx, prefix+name+"@test.com")
+		require.NoError(t, err)
+		return s, user.Id
+	}
+
+	t.Run("a disabled admin is refused at once", func(t *testing.T) {
+		s, id := other(t, "a")
+		ts.DoRequest(t, "POST", "/api/admin/users/"+id+"/disable", nil, admin.token, nil, 200)
+		var resp map[string]any
+		ts.DoRequest(t, "GET", "/api/admin/users", nil, s.token, &resp, 403)
+		require.Equal(t, "FORBIDDEN", resp["errorCode"])
+	})
+
+	t.Run("a logged out admin is refused at once", func(t *testing.T) {
+		s, id := other(t, "b")
+		ts.DoRequest(t, "POST", "/api/admin/users/"+id+"/logout", nil, admin.token, nil, 200)
+		ts.DoRequest(t, "GET", "/api/admin/users", nil, s.token, nil, 401)
+	})
+
+	t.Run("a demoted admin is refused at once", func(t *testing.T) {
+		s, id := other(t, "c")
+		require.NoError(t, ts.Store.UpdateRoles(ctx, id, []string{"user"}))
+		ts.DoRequest(t, "GET", "/api/admin/users", nil, s.token, nil, 403)
+	})
+}
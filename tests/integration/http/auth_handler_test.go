@@ -1,6 +1,7 @@
 package http_test
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -50,7 +51,7 @@ func TestAuthFlow(t *testing.T) {
 	t.Run("return get me when have token", func(t *testing.T) {
 		var meResp map[string]any
 		ts.DoRequest(t, "GET", "/api/me", nil, token, &meResp, 200)
-		require.Equal(t, "me", meResp["data"])
+		require.Equal(t, email, meResp["data"].(map[string]any)["email"])
 	})
 
 	t.Run("refresh returns new access token", func(t *testing.T) {
@@ -60,8 +61,9 @@ func TestAuthFlow(t *testing.T) {
 		}
 		var loginResp map[string]any
 		res := ts.DoRequest(t, "POST", "/api/auth/login", loginBody, "", &loginResp, 200)
+		csrf := map[string]string{"X-CSRF-Token": loginResp["data"].(map[string]any)["csrfToken"].(string)}
 		var refreshResp map[string]any
-		res = ts.DoRequest(t, "POST", "/api/auth/refresh", nil, "", &refreshResp, 200, res.Cookies()...)
+		res = ts.DoRequestWithHeaders(t, "POST", "/api/auth/refresh", nil, "", csrf, &refreshResp, 200, res.Cookies()...)
 		newToken := refreshResp["data"].(map[string]any)["token"].(string)
 		require.NotEmpty(t, newToken)
 		require.NotEqual(t, token, newToken)
@@ -83,8 +85,9 @@ func TestAuthFlow(t *testing.T) {
 		var loginResp map[string]any
 		res := ts.DoRequest(t, "POST", "/api/auth/login", loginBody, "", &loginResp, 200)
 		token := loginResp["data"].(map[string]any)["token"].(string)
+		csrf := map[string]string{"X-CSRF-Token": loginResp["data"].(map[string]any)["csrfToken"].(string)}
 		var logoutResp map[string]any
-		res = ts.DoRequest(t, "POST", "/api/auth/logout", nil, token, &logoutResp, 200, res.Cookies()...)
+		res = ts.DoRequestWithHeaders(t, "POST", "/api/auth/logout", nil, token, csrf, &logoutResp, 200, res.Cookies()...)
 		var cleared bool
 		for _, c := range res.Cookies() {
 			if c.Name == "refreshToken" && c.Value == "" && c.MaxAge == -1 {
@@ -112,6 +115,23 @@ func TestAuthFlow(t *testing.T) {
 		ts.DoRequest(t, "POST", "/api/auth/register", registerBody, "", &resp, 409)
 	})
 
+	t.Run("register with the email in another case", func(t *testing.T) {
+		registerBody := map[string]string{
+			"email":    strings.ToUpper(email),
+			"password": password,
+		}
+		var resp map[string]any
+		ts.DoRequest(t, "POST", "/api/auth/register", registerBody, "", &resp, 409)
+	})
+
+	t.Run("login ignores the case of the email", func(t *testing.T) {
+		loginBody := map[string]string{
+			"email":    strings.ToUpper(email),
+			"password": password,
+		}
+		ts.DoRequest(t, "POST", "/api/auth/login", loginBody, "", nil, 200)
+	})
+
 	t.Run("login with wrong password", func(t *testing.T) {
 		loginBody := map[string]string{
 			"email":    email,
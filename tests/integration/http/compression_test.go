@@ -0,0 +1,112 @@
+package http_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	_ "go-web/docs"
+	"go-web/tests/utils"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression(t *testing.T) {
+	ts := utils.SetupTestServer()
+	defer ts.Server.Close()
+
+	get := func(t *testing.T, path, acceptEncoding string) (*http.Response, []byte) {
+		req, err := http.NewRequest("GET", ts.Server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		res, err := ts.Client.Do(req)
+		require.NoError(t, err)
+		//nolint:errcheck
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, body
+	}
+
+	t.Run("gzip response", func(t *testing.T) {
+		res, body := get(t, "/docs/doc.json", "gzip")
+		require.Equal(t, 200, res.StatusCode)
+		require.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+		require.Contains(t, res.Header.Values("Vary"), "Accept-Encoding")
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		decoded, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		require.True(t, json.Valid(decoded))
+	})
+
+	t.Run("zstd preferred over gzip", func(t *testing.T) {
+		res, body := get(t, "/docs/doc.json", "gzip, deflate, br, zstd")
+		require.Equal(t, "zstd", res.Header.Get("Content-Encoding"))
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		defer zr.Close()
+		decoded, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		require.True(t, json.Valid(decoded))
+	})
+
+	t.Run("client quality values win", func(t *testing.T) {
+		res, _ := get(t, "/docs/doc.json", "zstd;q=0.5, gzip;q=0.9")
+		require.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+	})
+
+	t.Run("q=0 falls back to identity", func(t *testing.T) {
+		res, body := get(t, "/docs/doc.json", "gzip;q=0, zstd;q=0")
+		require.Empty(t, res.Header.Get("Content-Encoding"))
+		require.True(t, json.Valid(body))
+	})
+
+	t.Run("small response is not compressed", func(t *testing.T) {
+		res, body := get(t, "/api/example", "gzip, zstd")
+		require.Empty(t, res.Header.Get("Content-Encoding"))
+		require.Contains(t, res.Header.Values("Vary"), "Accept-Encoding")
+		require.True(t, json.Valid(body))
+	})
+
+	post := func(t *testing.T, body []byte, encoding string) *http.Response {
+		req, err := http.NewRequest("POST", ts.Server.URL+"/api/auth/register", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", encoding)
+		res, err := ts.Client.Do(req)
+		require.NoError(t, err)
+		//nolint:errcheck
+		res.Body.Close()
+		return res
+	}
+
+	t.Run("gzip request body is decoded", func(t *testing.T) {
+		payload, err := json.Marshal(map[string]string{
+			"email":    "gzip" + utils.GenUserEmail(),
+			"password": "password123",
+		})
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err = zw.Write(payload)
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		res := post(t, buf.Bytes(), "gzip")
+		require.Equal(t, 201, res.StatusCode)
+	})
+
+	t.Run("corrupt gzip request body is rejected", func(t *testing.T) {
+		res := post(t, []byte("not gzip"), "gzip")
+		require.Equal(t, 400, res.StatusCode)
+	})
+
+	t.Run("unsupported request encoding is rejected", func(t *testing.T) {
+		res := post(t, []byte("{}"), "br")
+		require.Equal(t, 415, res.StatusCode)
+	})
+}
@@ -0,0 +1,89 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-web/internal/platform"
+	httpTransport "go-web/internal/transport/http"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const corsConfig = `
+cors:
+    allowed_origins: ["https://app.example.com"]
+    groups:
+        /docs/:
+            allowed_origins: ["https://docs.example.com"]
+`
+
+// preflight returns the origin allowed by the preflight of path from origin,
+// empty when the origin is rejected.
+func preflight(t *testing.T, url, path, origin string) string {
+	req, err := http.NewRequest(http.MethodOptions, url+path, nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	//nolint:errcheck
+	defer res.Body.Close()
+	return res.Header.Get("Access-Control-Allow-Origin")
+}
+
+func TestCorsPolicies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(corsConfig), 0o600))
+	args := []string{"-config", path}
+	cfg, err := platform.LoadConfig(args)
+	require.NoError(t, err)
+	watcher := platform.NewConfigWatcher(cfg, args)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	ts := httptest.NewServer(httpTransport.CorsHandler(watcher, ok))
+	defer ts.Close()
+
+	t.Run("should apply the policy of the route group", func(t *testing.T) {
+		assert.Equal(t, "https://app.example.com", preflight(t, ts.URL, "/api/users/me", "https://app.example.com"))
+		assert.Empty(t, preflight(t, ts.URL, "/api/users/me", "https://docs.example.com"))
+		assert.Equal(t, "https://docs.example.com", preflight(t, ts.URL, "/docs/index.html", "https://docs.example.com"))
+		assert.Empty(t, preflight(t, ts.URL, "/docs/index.html", "https://app.example.com"))
+	})
+
+	t.Run("should refuse credentials for any origin", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(`
+cors:
+    allowed_origins: ["https://app.example.com"]
+    groups:
+        /docs/:
+            allowed_origins: ["*"]
+            allow_credentials: true
+`), 0o600))
+		assert.ErrorContains(t, watcher.Reload(), "credentials cannot be allowed for any origin")
+
+		require.NoError(t, os.WriteFile(path, []byte("cors:\n    allowed_origins: [\"*\"]\n    allow_credentials: true\n"), 0o600))
+		assert.ErrorContains(t, watcher.Reload(), "credentials cannot be allowed for any origin")
+
+		assert.Empty(t, preflight(t, ts.URL, "/docs/index.html", "https://evil.example.com"), "rejected reloads keep the policies")
+	})
+
+	t.Run("should swap the policies on reload", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(`
+cors:
+    allowed_origins: ["https://new.example.com"]
+    groups:
+        /docs/:
+            allowed_origins: ["*"]
+`), 0o600))
+		require.NoError(t, watcher.Reload())
+
+		assert.Equal(t, "https://new.example.com", preflight(t, ts.URL, "/api/users/me", "https://new.example.com"))
+		assert.Empty(t, preflight(t, ts.URL, "/api/users/me", "https://app.example.com"))
+		assert.Equal(t, "*", preflight(t, ts.URL, "/docs/index.html", "https://evil.example.com"))
+	})
+}
@@ -0,0 +1,111 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"testing"
+
+	"go-web/tests/utils"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCsrfProtection(t *testing.T) {
+	ts := utils.SetupTestServer()
+	defer ts.Server.Close()
+
+	credentials := map[string]string{
+		"email":    "csrf" + utils.GenUserEmail(),
+		"password": "password123",
+	}
+	ts.DoRequest(t, "POST", "/api/auth/register", credentials, "", nil, 201)
+
+	login := func(t *testing.T) (string, string, []*http.Cookie) {
+		var loginResp map[string]any
+		res := ts.DoRequest(t, "POST", "/api/auth/login", credentials, "", &loginResp, 200)
+		data := loginResp["data"].(map[string]any)
+		csrfToken := data["csrfToken"].(string)
+		require.NotEmpty(t, csrfToken)
+		var hasCsrfCookie bool
+		for _, c := range res.Cookies() {
+			if c.Name == "csrfToken" {
+				hasCsrfCookie = true
+				require.Equal(t, csrfToken, c.Value)
+			}
+		}
+		require.True(t, hasCsrfCookie, "csrfToken cookie must be set")
+		return data["token"].(string), csrfToken, res.Cookies()
+	}
+
+	t.Run("refresh without csrf header is forbidden", func(t *testing.T) {
+		_, _, cookies := login(t)
+		var resp map[string]any
+		ts.DoRequest(t, "POST", "/api/auth/refresh", nil, "", &resp, 403, cookies...)
+		require.Equal(t, "FORBIDDEN", resp["errorCode"])
+	})
+
+	t.Run("refresh with mismatched csrf header is forbidden", func(t *testing.T) {
+		_, _, cookies := login(t)
+		headers := map[string]string{"X-CSRF-Token": "forged"}
+		var resp map[string]any
+		ts.DoRequestWithHeaders(t, "POST", "/api/auth/refresh", nil, "", headers, &resp, 403, cookies...)
+		require.Equal(t, "FORBIDDEN", resp["errorCode"])
+	})
+
+	t.Run("refresh from untrusted origin is forbidden", func(t *testing.T) {
+		_, csrfToken, cookies := login(t)
+		headers := map[string]string{"X-CSRF-Token": csrfToken, "Origin": "https://evil.example"}
+		var resp map[string]any
+		ts.DoRequestWithHeaders(t, "POST", "/api/auth/refresh", nil, "", headers, &resp, 403, cookies...)
+		require.Equal(t, "FORBIDDEN", resp["errorCode"])
+	})
+
+	t.Run("refresh marked cross-site without origin is forbidden", func(t *testing.T) {
+		_, csrfToken, cookies := login(t)
+		headers := map[string]string{"X-CSRF-Token": csrfToken, "Sec-Fetch-Site": "cross-site"}
+		ts.DoRequestWithHeaders(t, "POST", "/api/auth/refresh", nil, "", headers, nil, 403, cookies...)
+	})
+
+	t.Run("refresh with valid csrf token from same origin succeeds", func(t *testing.T) {
+		_, csrfToken, cookies := login(t)
+		headers := map[string]string{"X-CSRF-Token": csrfToken, "Origin": ts.Server.URL, "Sec-Fetch-Site": "same-origin"}
+		var resp map[string]any
+		ts.DoRequestWithHeaders(t, "POST", "/api/auth/refresh", nil, "", headers, &resp, 200, cookies...)
+		newCsrfToken := resp["data"].(map[string]any)["csrfToken"].(string)
+		require.NotEmpty(t, newCsrfToken)
+		require.NotEqual(t, csrfToken, newCsrfToken, "csrf token must be rotated on refresh")
+	})
+
+	t.Run("logout without csrf header is forbidden", func(t *testing.T) {
+		token, _, cookies := login(t)
+		ts.DoRequest(t, "POST", "/api/auth/logout", nil, token, nil, 403, cookies...)
+	})
+
+	t.Run("logout with valid csrf token succeeds", func(t *testing.T) {
+		token, csrfToken, cookies := login(t)
+		headers := map[string]string{"X-CSRF-Token": csrfToken}
+		ts.DoRequestWithHeaders(t, "POST", "/api/auth/logout", nil, token, headers, nil, 200, cookies...)
+	})
+
+	t.Run("cookies reach refresh and logout from a browser's jar", func(t *testing.T) {
+		jar, err := cookiejar.New(nil)
+		require.NoError(t, err)
+		browser := *ts
+		browser.Client = &http.Client{Jar: jar}
+
+		var loginResp map[string]any
+		browser.DoRequest(t, "POST", "/api/auth/login", credentials, "", &loginResp, 200)
+		csrfToken := loginResp["data"].(map[string]any)["csrfToken"].(string)
+
+		var refreshResp map[string]any
+		browser.DoRequestWithHeaders(t, "POST", "/api/auth/refresh", nil, "", map[string]string{"X-CSRF-Token": csrfToken}, &refreshResp, 200)
+		data := refreshResp["data"].(map[string]any)
+		headers := map[string]string{"X-CSRF-Token": data["csrfToken"].(string)}
+
+		browser.DoRequestWithHeaders(t, "POST", "/api/auth/logout", nil, data["token"].(string), headers, nil, 200)
+		refreshURL, err := url.Parse(ts.Server.URL + "/api/auth/refresh")
+		require.NoError(t, err)
+		require.Empty(t, jar.Cookies(refreshURL), "logout must clear the cookies it set")
+	})
+}
@@ -0,0 +1,155 @@
+package http_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	_ "go-web/docs"
+	httpTransport "go-web/internal/transport/http"
+	"go-web/tests/utils"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestETag(t *testing.T) {
+	ts := utils.SetupTestServer()
+	defer ts.Server.Close()
+
+	get := func(t *testing.T, path string, headers map[string]string) (*http.Response, []byte) {
+		req, err := http.NewRequest("GET", ts.Server.URL+path, nil)
+		require.NoError(t, err)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		res, err := ts.Client.Do(req)
+		require.NoError(t, err)
+		//nolint:errcheck
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, body
+	}
+
+	t.Run("get returns a strong etag", func(t *testing.T) {
+		res, _ := get(t, "/api/example", nil)
+		require.Equal(t, 200, res.StatusCode)
+		etag := res.Header.Get("ETag")
+		require.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+
+		again, _ := get(t, "/api/example", nil)
+		require.Equal(t, etag, again.Header.Get("ETag"))
+	})
+
+	t.Run("matching if-none-match is not modified", func(t *testing.T) {
+		res, _ := get(t, "/api/example", nil)
+		etag := res.Header.Get("ETag")
+
+		res, body := get(t, "/api/example", map[string]string{"If-None-Match": `"other", ` + etag})
+		require.Equal(t, 304, res.StatusCode)
+		require.Empty(t, body)
+		require.Equal(t, etag, res.Header.Get("ETag"))
+	})
+
+	t.Run("stale if-none-match gets the full body", func(t *testing.T) {
+		res, body := get(t, "/api/example", map[string]string{"If-None-Match": `"stale"`})
+		require.Equal(t, 200, res.StatusCode)
+		require.NotEmpty(t, body)
+	})
+
+	t.Run("compressed responses use weak etags", func(t *testing.T) {
+		res, _ := get(t, "/docs/doc.json", map[string]string{"Accept-Encoding": "gzip"})
+		require.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+		etag := res.Header.Get("ETag")
+		require.Regexp(t, `^W/"[0-9a-f]{32}"$`, etag)
+
+		res, _ = get(t, "/docs/doc.json", map[string]string{"Accept-Encoding": "gzip", "If-None-Match": etag})
+		require.Equal(t, 304, res.StatusCode)
+	})
+
+	t.Run("errors carry no etag", func(t *testing.T) {
+		res, _ := get(t, "/api/error", nil)
+		require.Equal(t, 500, res.StatusCode)
+		require.Empty(t, res.Header.Get("ETag"))
+	})
+}
+
+func TestIfMatchCompressed(t *testing.T) {
+	ts := utils.SetupTestServer()
+	defer ts.Server.Close()
+
+	email := "ifmatch" + utils.GenUserEmail()
+	ts.DoRequest(t, "POST", "/api/auth/register", map[string]string{"email": email, "password": "password123"}, "", nil, 201)
+	s := login(t, ts, email, "password123")
+	// A long avatar url makes the profile large enough to be compressed.
+	avatar := "https://cdn.example.com/" + strings.Repeat("a", 1500) + ".png"
+	ts.DoRequest(t, "PATCH", "/api/me", map[string]string{"avatarUrl": avatar}, s.token, nil, 200)
+
+	gzip := map[string]string{"Accept-Encoding": "gzip"}
+	res := ts.DoRequestWithHeaders(t, "GET", "/api/me", nil, s.token, gzip, nil, 200)
+	require.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+	etag := res.Header.Get("ETag")
+	require.True(t, strings.HasPrefix(etag, "W/"), "compression weakens the etag")
+
+	headers := map[string]string{"Accept-Encoding": "gzip", "If-Match": etag}
+	ts.DoRequestWithHeaders(t, "PATCH", "/api/me", map[string]string{"displayName": "Jane"}, s.token, headers, nil, 200)
+	ts.DoRequestWithHeaders(t, "PATCH", "/api/me", map[string]string{"displayName": "John"}, s.token, headers, nil, 412)
+}
+
+func TestETagStreaming(t *testing.T) {
+	serve := func(h http.HandlerFunc, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		httpTransport.ETagMiddleware(h).ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("large bodies are streamed without etag", func(t *testing.T) {
+		body := bytes.Repeat([]byte("a"), 3<<20)
+		rec := serve(func(w http.ResponseWriter, r *http.Request) {
+			for i := 0; i < len(body); i += 64 << 10 {
+				//nolint:errcheck
+				w.Write(body[i : i+64<<10])
+			}
+		}, "")
+		require.Equal(t, 200, rec.Code)
+		require.Empty(t, rec.Header().Get("ETag"))
+		require.Equal(t, body, rec.Body.Bytes())
+	})
+
+	t.Run("flushes reach the client", func(t *testing.T) {
+		rec := serve(func(w http.ResponseWriter, r *http.Request) {
+			//nolint:errcheck
+			w.Write([]byte("first"))
+			w.(http.Flusher).Flush()
+			//nolint:errcheck
+			w.Write([]byte(" second"))
+		}, "")
+		require.True(t, rec.Flushed)
+		require.Empty(t, rec.Header().Get("ETag"))
+		require.Equal(t, "first second", rec.Body.String())
+	})
+
+	t.Run("etags set by the handler are checked without buffering", func(t *testing.T) {
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Type", "text/plain")
+			//nolint:errcheck
+			w.Write([]byte("version one"))
+		}
+		rec := serve(handler, "")
+		require.Equal(t, `"v1"`, rec.Header().Get("ETag"))
+		require.Equal(t, "version one", rec.Body.String())
+
+		rec = serve(handler, `W/"v1"`)
+		require.Equal(t, 304, rec.Code)
+		require.Empty(t, rec.Body.String())
+		require.Empty(t, rec.Header().Get("Content-Type"))
+	})
+}
@@ -0,0 +1,42 @@
+package http_test
+
+import (
+	"context"
+	"testing"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/service"
+	"go-web/internal/infra/publisher"
+	"go-web/tests/utils"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventsRelay(t *testing.T) {
+	ts := utils.SetupTestServer()
+	defer ts.Server.Close()
+
+	ctx := context.Background()
+	email := "events" + utils.GenUserEmail()
+	ts.DoRequest(t, "POST", "/api/auth/register", map[string]string{"email": email, "password": "password123"}, "", nil, 201)
+	login(t, ts, email, "password123")
+
+	user, err := ts.Store.FindByEmail(ctx, email)
+	require.NoError(t, err)
+
+	pub := publisher.NewMemPublisher()
+	events := service.NewEventService(ts.Store, pub)
+	n, err := events.RelayEvents(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, n, 2)
+
+	var types []string
+	for _, e := range pub.Published(user.Id) {
+		types = append(types, e.Type)
+	}
+	require.Equal(t, []string{models.EventUserRegistered, models.EventUserLoggedIn}, types)
+
+	n, err = events.RelayEvents(ctx)
+	require.NoError(t, err)
+	require.Zero(t, n, "published events are not relayed again")
+}
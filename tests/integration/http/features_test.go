@@ -0,0 +1,39 @@
+package http_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-web/internal/platform"
+	"go-web/tests/utils"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatureToggleReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("features:\n    signup: true\n"), 0o600))
+	args := []string{"-config", path}
+	cfg, err := platform.LoadConfig(args)
+	require.NoError(t, err)
+	watcher := platform.NewConfigWatcher(cfg, args)
+
+	ts := utils.SetupTestServerWithFeatures(watcher.FeatureEnabled)
+	defer ts.Server.Close()
+
+	register := func(round int, wantStatus int) {
+		body := map[string]string{"email": fmt.Sprintf("toggle%d", round) + utils.GenUserEmail(), "password": "password123"}
+		ts.DoRequest(t, "POST", "/api/auth/register", body, "", nil, wantStatus)
+	}
+	register(0, 201)
+
+	require.NoError(t, os.WriteFile(path, []byte("features:\n    signup: false\n"), 0o600))
+	require.NoError(t, watcher.Reload())
+	register(1, 403)
+
+	require.NoError(t, os.WriteFile(path, []byte("features: {}\n"), 0o600))
+	require.NoError(t, watcher.Reload())
+	register(2, 201)
+}
@@ -0,0 +1,56 @@
+package http_test
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"go-web/internal/platform"
+	"go-web/tests/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestH2C(t *testing.T) {
+	ts := utils.SetupH2CTestServer(platform.HTTP2Config{H2C: true, MaxConcurrentStreams: 10})
+	defer ts.Server.Close()
+
+	t.Run("serves http2 over cleartext with prior knowledge", func(t *testing.T) {
+		var resp map[string]any
+		res := ts.DoRequest(t, "GET", "/api/example", nil, "", &resp, 200)
+		require.Equal(t, 2, res.ProtoMajor)
+		require.Equal(t, "Hello World", resp["data"])
+	})
+
+	t.Run("multiplexes concurrent requests", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := ts.Client.Get(ts.Server.URL + "/api/example")
+				if !assert.NoError(t, err) {
+					return
+				}
+				//nolint:errcheck
+				defer res.Body.Close()
+				//nolint:errcheck
+				io.Copy(io.Discard, res.Body)
+				assert.Equal(t, http.StatusOK, res.StatusCode)
+				assert.Equal(t, "HTTP/2.0", res.Proto)
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("still serves http1 clients", func(t *testing.T) {
+		res, err := ts.Server.Client().Get(ts.Server.URL + "/api/example")
+		require.NoError(t, err)
+		//nolint:errcheck
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, 1, res.ProtoMajor)
+	})
+}
@@ -0,0 +1,104 @@
+package http_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-web/tests/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	ts := utils.SetupTestServer()
+	defer ts.Server.Close()
+
+	t.Run("retry replays the first response", func(t *testing.T) {
+		key := "replay-" + utils.GenUserEmail()
+		credentials := map[string]string{"email": "idem" + utils.GenUserEmail(), "password": "password123"}
+		headers := map[string]string{"Idempotency-Key": key}
+
+		var first, second map[string]any
+		res := ts.DoRequestWithHeaders(t, "POST", "/api/auth/register", credentials, "", headers, &first, 201)
+		require.Empty(t, res.Header.Get("Idempotent-Replayed"))
+		res = ts.DoRequestWithHeaders(t, "POST", "/api/auth/register", credentials, "", headers, &second, 201)
+		require.Equal(t, "true", res.Header.Get("Idempotent-Replayed"))
+		require.Equal(t, first, second)
+
+		// Without the key the same payload hits the handler again.
+		ts.DoRequest(t, "POST", "/api/auth/register", credentials, "", nil, 409)
+	})
+
+	t.Run("anonymous clients picking the same key do not share responses", func(t *testing.T) {
+		headers := map[string]string{"Idempotency-Key": "shared-" + utils.GenUserEmail()}
+		credentials := map[string]string{"email": "idem1" + utils.GenUserEmail(), "password": "password123"}
+		ts.DoRequestWithHeaders(t, "POST", "/api/auth/register", credentials, "", headers, nil, 201)
+
+		credentials["email"] = "idem2" + utils.GenUserEmail()
+		var resp map[string]any
+		res := ts.DoRequestWithHeaders(t, "POST", "/api/auth/register", credentials, "", headers, &resp, 201)
+		require.Empty(t, res.Header.Get("Idempotent-Replayed"))
+		require.Equal(t, credentials["email"], resp["data"].(map[string]any)["email"])
+	})
+
+	t.Run("oversized body is refused", func(t *testing.T) {
+		headers := map[string]string{"Idempotency-Key": "large-" + utils.GenUserEmail()}
+		credentials := map[string]string{"email": "idem4" + utils.GenUserEmail(), "password": strings.Repeat("p", 2<<20)}
+		var resp map[string]any
+		ts.DoRequestWithHeaders(t, "POST", "/api/auth/register", credentials, "", headers, &resp, 400)
+		require.Equal(t, "INVALID_BODY", resp["errorCode"])
+	})
+
+	t.Run("concurrent duplicates are rejected", func(t *testing.T) {
+		body, err := json.Marshal(map[string]string{"email": "idem3" + utils.GenUserEmail(), "password": "password123"})
+		require.NoError(t, err)
+		key := "concurrent-" + utils.GenUserEmail()
+
+		type result struct {
+			status   int
+			replayed bool
+		}
+		results := make([]result, 10)
+		var wg sync.WaitGroup
+		for i := range results {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req, err := http.NewRequest("POST", ts.Server.URL+"/api/auth/register", bytes.NewReader(body))
+				if !assert.NoError(t, err) {
+					return
+				}
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Idempotency-Key", key)
+				res, err := ts.Client.Do(req)
+				if !assert.NoError(t, err) {
+					return
+				}
+				//nolint:errcheck
+				defer res.Body.Close()
+				//nolint:errcheck
+				io.Copy(io.Discard, res.Body)
+				results[i] = result{res.StatusCode, res.Header.Get("Idempotent-Replayed") == "true"}
+			}()
+		}
+		wg.Wait()
+
+		var created int
+		for _, r := range results {
+			switch {
+			case r.status == 201 && !r.replayed:
+				created++
+			case r.status == 201 && r.replayed, r.status == 409:
+			default:
+				t.Fatalf("unexpected status %d", r.status)
+			}
+		}
+		require.Equal(t, 1, created)
+	})
+}
@@ -0,0 +1,99 @@
+package http_test
+
+import (
+	"strings"
+	"testing"
+
+	"go-web/tests/utils"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMe(t *testing.T) {
+	ts := utils.SetupTestServer()
+	defer ts.Server.Close()
+
+	credentials := map[string]string{
+		"email":    "me" + utils.GenUserEmail(),
+		"password": "password123",
+	}
+	ts.DoRequest(t, "POST", "/api/auth/register", credentials, "", nil, 201)
+	var loginResp map[string]any
+	ts.DoRequest(t, "POST", "/api/auth/login", credentials, "", &loginResp, 200)
+	token := loginResp["data"].(map[string]any)["token"].(string)
+
+	var etag string
+	t.Run("get returns the profile", func(t *testing.T) {
+		var resp map[string]any
+		res := ts.DoRequest(t, "GET", "/api/me", nil, token, &resp, 200)
+		data := resp["data"].(map[string]any)
+		require.NotEmpty(t, data["id"])
+		require.Equal(t, credentials["email"], data["email"])
+		require.Equal(t, false, data["emailVerified"])
+		require.Equal(t, false, data["twoFactorEnabled"])
+		require.Equal(t, []any{"user"}, data["roles"])
+		require.NotEmpty(t, data["createdAt"])
+		require.NotEmpty(t, data["updatedAt"])
+		require.NotEmpty(t, data["lastLoginAt"], "login must record last_login_at")
+		etag = res.Header.Get("ETag")
+		require.NotEmpty(t, etag)
+	})
+
+	t.Run("unchanged profile is not modified", func(t *testing.T) {
+		headers := map[string]string{"If-None-Match": etag}
+		ts.DoRequestWithHeaders(t, "GET", "/api/me", nil, token, headers, nil, 304)
+	})
+
+	t.Run("patch with invalid body", func(t *testing.T) {
+		body := map[string]string{"displayName": strings.Repeat("a", 65)}
+		var resp map[string]any
+		ts.DoRequest(t, "PATCH", "/api/me", body, token, &resp, 400)
+		require.Equal(t, "INVALID_BODY", resp["errorCode"])
+	})
+
+	t.Run("patch updates the profile", func(t *testing.T) {
+		body := map[string]string{"displayName": "Jane"}
+		headers := map[string]string{"If-Match": etag}
+		var resp map[string]any
+		res := ts.DoRequestWithHeaders(t, "PATCH", "/api/me", body, token, headers, &resp, 200)
+		require.Equal(t, "Jane", resp["data"].(map[string]any)["displayName"])
+		require.NotEqual(t, etag, res.Header.Get("ETag"))
+	})
+
+	t.Run("patch sets avatar, locale and timezone", func(t *testing.T) {
+		body := map[string]string{
+			"avatarUrl": "https://cdn.example.com/a.png",
+			"locale":    "pt-BR",
+			"timezone":  "America/Sao_Paulo",
+		}
+		var resp map[string]any
+		ts.DoRequest(t, "PATCH", "/api/me", body, token, &resp, 200)
+		data := resp["data"].(map[string]any)
+		require.Equal(t, "https://cdn.example.com/a.png", data["avatarUrl"])
+		require.Equal(t, "pt-BR", data["locale"])
+		require.Equal(t, "America/Sao_Paulo", data["timezone"])
+		require.Equal(t, "Jane", data["displayName"], "fields not sent are kept")
+	})
+
+	t.Run("patch rejects invalid preferences", func(t *testing.T) {
+		for _, body := range []map[string]string{
+			{"avatarUrl": "javascript:alert(1)"},
+			{"locale": "not a locale"},
+			{"timezone": "Mars/Olympus_Mons"},
+		} {
+			ts.DoRequest(t, "PATCH", "/api/me", body, token, nil, 400)
+		}
+	})
+
+	t.Run("patch with a stale etag fails", func(t *testing.T) {
+		body := map[string]string{"displayName": "John"}
+		headers := map[string]string{"If-Match": etag}
+		var resp map[string]any
+		ts.DoRequestWithHeaders(t, "PATCH", "/api/me", body, token, headers, &resp, 412)
+		require.Equal(t, "PRECONDITION_FAILED", resp["errorCode"])
+	})
+
+	t.Run("patch without token", func(t *testing.T) {
+		ts.DoRequest(t, "PATCH", "/api/me", map[string]string{"displayName": "x"}, "", nil, 401)
+	})
+}
@@ -0,0 +1,57 @@
+package http_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"go-web/tests/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrentRegistration(t *testing.T) {
+	ts := utils.SetupTestServer()
+	defer ts.Server.Close()
+
+	// Both requests may pass the lookup of the email before either inserts;
+	// the unique constraint must still let a single one through.
+	for round := 0; round < 5; round++ {
+		body, err := json.Marshal(map[string]string{"email": fmt.Sprintf("race%d", round) + utils.GenUserEmail(), "password": "password123"})
+		require.NoError(t, err)
+
+		statuses := make([]int, 2)
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := range statuses {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req, err := http.NewRequest("POST", ts.Server.URL+"/api/auth/register", bytes.NewReader(body))
+				if !assert.NoError(t, err) {
+					return
+				}
+				req.Header.Set("Content-Type", "application/json")
+				<-start
+				res, err := ts.Client.Do(req)
+				if !assert.NoError(t, err) {
+					return
+				}
+				//nolint:errcheck
+				defer res.Body.Close()
+				//nolint:errcheck
+				io.Copy(io.Discard, res.Body)
+				statuses[i] = res.StatusCode
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		require.ElementsMatch(t, []int{201, 409}, statuses)
+	}
+}
@@ -0,0 +1,65 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-web/internal/platform"
+	httpTransport "go-web/internal/transport/http"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	cfg, err := platform.LoadConfig(nil)
+	require.NoError(t, err)
+	headers := cfg.SecurityHeaders
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(cfg platform.SecurityHeadersConfig, req *http.Request) http.Header {
+		rec := httptest.NewRecorder()
+		httpTransport.SecurityHeadersMiddleware(cfg)(ok).ServeHTTP(rec, req)
+		return rec.Header()
+	}
+
+	t.Run("should apply the api profile outside of /docs/", func(t *testing.T) {
+		h := serve(headers, httptest.NewRequest(http.MethodGet, "https://localhost/api/users/me", nil))
+		assert.Equal(t, "DENY", h.Get("X-Frame-Options"))
+		assert.Equal(t, "default-src 'none'; frame-ancestors 'none'", h.Get("Content-Security-Policy"))
+		assert.Equal(t, "nosniff", h.Get("X-Content-Type-Options"))
+		assert.Equal(t, "no-referrer", h.Get("Referrer-Policy"))
+	})
+
+	t.Run("should apply the docs profile under /docs/", func(t *testing.T) {
+		h := serve(headers, httptest.NewRequest(http.MethodGet, "https://localhost/docs/index.html", nil))
+		assert.Equal(t, "SAMEORIGIN", h.Get("X-Frame-Options"))
+		assert.Equal(t, headers.Docs.ContentSecurityPolicy, h.Get("Content-Security-Policy"))
+		assert.Contains(t, h.Get("Content-Security-Policy"), "script-src 'self' 'unsafe-inline'")
+	})
+
+	t.Run("should send hsts only over tls", func(t *testing.T) {
+		h := serve(headers, httptest.NewRequest(http.MethodGet, "https://localhost/api/users/me", nil))
+		assert.Equal(t, "max-age=15552000; includeSubDomains", h.Get("Strict-Transport-Security"))
+
+		h = serve(headers, httptest.NewRequest(http.MethodGet, "http://localhost/api/users/me", nil))
+		assert.Empty(t, h.Get("Strict-Transport-Security"))
+
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/api/users/me", nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		assert.Empty(t, serve(headers, req).Get("Strict-Transport-Security"), "the proxy header is not trusted by default")
+
+		trusted := headers
+		trusted.HSTS.TrustForwardedProto = true
+		trusted.HSTS.Preload = true
+		assert.Equal(t, "max-age=15552000; includeSubDomains; preload", serve(trusted, req).Get("Strict-Transport-Security"))
+	})
+
+	t.Run("should send nothing when disabled", func(t *testing.T) {
+		disabled := headers
+		disabled.Enabled = false
+		h := serve(disabled, httptest.NewRequest(http.MethodGet, "https://localhost/api/users/me", nil))
+		assert.Empty(t, h)
+	})
+}
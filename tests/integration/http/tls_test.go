@@ -0,0 +1,239 @@
+package http_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-web/internal/platform"
+	httpTransport "go-web/internal/transport/http"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testCA issues throwaway certificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert, key, pool, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM encoded certificate and key of a leaf named cn.
+func (ca *testCA) issue(t *testing.T, cn string, serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"go-web"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (ca *testCA) clientCert(t *testing.T, cn string) tls.Certificate {
+	certPEM, keyPEM := ca.issue(t, cn, 100, x509.ExtKeyUsageClientAuth)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	return cert
+}
+
+// writeFiles writes the files and dates them at, so that the reloader sees
+// them change even within the resolution of the file system clock.
+func writeFiles(t *testing.T, at time.Time, files map[string][]byte) {
+	for path, data := range files {
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+		require.NoError(t, os.Chtimes(path, at, at))
+	}
+}
+
+// serveTLS serves the identity of the client certificate, if any, over TLS
+// configured like RunServer does.
+func serveTLS(t *testing.T, cfg platform.TLSConfig) string {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	tlsCfg, err := httpTransport.NewTLSConfig(ctx, cfg)
+	require.NoError(t, err)
+
+	handler := httpTransport.ClientCertMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := httpTransport.ClientIdentityFrom(r.Context())
+		if !ok {
+			//nolint:errcheck
+			io.WriteString(w, "anonymous")
+			return
+		}
+		//nolint:errcheck
+		io.WriteString(w, id.CommonName+" "+id.Organization[0]+" "+id.SerialNumber)
+	}))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &http.Server{Handler: handler, TLSConfig: tlsCfg, ReadHeaderTimeout: time.Second}
+	go func() {
+		//nolint:errcheck
+		srv.ServeTLS(ln, "", "")
+	}()
+	t.Cleanup(func() {
+		//nolint:errcheck
+		srv.Close()
+	})
+	return "https://" + ln.Addr().String()
+}
+
+func tlsClient(ca *testCA, certs ...tls.Certificate) *http.Client {
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: ca.pool, ServerName: "localhost", Certificates: certs},
+			DisableKeepAlives: true,
+		},
+	}
+}
+
+func getBody(client *http.Client, url string) (string, error) {
+	res, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	//nolint:errcheck
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	return string(body), err
+}
+
+func TestTLSCertificateReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	certPEM, keyPEM := ca.issue(t, "server-a", 10, x509.ExtKeyUsageServerAuth)
+	writeFiles(t, time.Now(), map[string][]byte{certFile: certPEM, keyFile: keyPEM})
+
+	url := serveTLS(t, platform.TLSConfig{
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		MinVersion:     "1.2",
+		ReloadInterval: 10 * time.Millisecond,
+		ClientAuth:     "none",
+	})
+	served := func() string {
+		conn, err := tls.Dial("tcp", url[len("https://"):], &tls.Config{RootCAs: ca.pool, ServerName: "localhost"})
+		require.NoError(t, err)
+		//nolint:errcheck
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+	require.Equal(t, "server-a", served())
+
+	t.Run("rotated files are served", func(t *testing.T) {
+		certPEM, keyPEM := ca.issue(t, "server-b", 11, x509.ExtKeyUsageServerAuth)
+		writeFiles(t, time.Now().Add(time.Second), map[string][]byte{certFile: certPEM, keyFile: keyPEM})
+		require.Eventually(t, func() bool { return served() == "server-b" }, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("invalid files keep the previous certificate", func(t *testing.T) {
+		writeFiles(t, time.Now().Add(2*time.Second), map[string][]byte{keyFile: []byte("not a key")})
+		time.Sleep(50 * time.Millisecond)
+		require.Equal(t, "server-b", served())
+	})
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	certPEM, keyPEM := ca.issue(t, "server", 10, x509.ExtKeyUsageServerAuth)
+	writeFiles(t, time.Now(), map[string][]byte{certFile: certPEM, keyFile: keyPEM, caFile: ca.pem})
+	cfg := platform.TLSConfig{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		MinVersion:   "1.2",
+		ClientCAFile: caFile,
+	}
+	client := ca.clientCert(t, "billing")
+	stranger := newTestCA(t).clientCert(t, "stranger")
+
+	t.Run("require rejects clients without a trusted certificate", func(t *testing.T) {
+		cfg := cfg
+		cfg.ClientAuth = "require"
+		url := serveTLS(t, cfg)
+
+		_, err := getBody(tlsClient(ca), url)
+		require.Error(t, err)
+		_, err = getBody(tlsClient(ca, stranger), url)
+		require.Error(t, err)
+
+		body, err := getBody(tlsClient(ca, client), url)
+		require.NoError(t, err)
+		require.Equal(t, "billing go-web 100", body)
+	})
+
+	t.Run("request lets clients without a certificate in", func(t *testing.T) {
+		cfg := cfg
+		cfg.ClientAuth = "request"
+		url := serveTLS(t, cfg)
+
+		body, err := getBody(tlsClient(ca), url)
+		require.NoError(t, err)
+		require.Equal(t, "anonymous", body)
+
+		body, err = getBody(tlsClient(ca, client), url)
+		require.NoError(t, err)
+		require.Equal(t, "billing go-web 100", body)
+
+		_, err = getBody(tlsClient(ca, stranger), url)
+		require.Error(t, err, "certificates given must still be trusted")
+	})
+
+	t.Run("none ignores client certificates", func(t *testing.T) {
+		cfg := cfg
+		cfg.ClientAuth = "none"
+		url := serveTLS(t, cfg)
+
+		body, err := getBody(tlsClient(ca, client), url)
+		require.NoError(t, err)
+		require.Equal(t, "anonymous", body)
+	})
+}
@@ -0,0 +1,86 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s ports.Store) {
+		ctx := context.Background()
+		now := time.Now()
+		a, b := uuid.NewString(), uuid.NewString()
+		add := func(aggregateId string) string {
+			id := uuid.NewString()
+			require.NoError(t, s.AddEvent(ctx, &models.Event{
+				Id:          id,
+				Type:        models.EventUserLoggedIn,
+				AggregateId: aggregateId,
+				Payload:     []byte(`{}`),
+				OccurredAt:  now.Add(-time.Minute),
+			}))
+			return id
+		}
+		// claim returns the ids of the events of a and b claimed at the given
+		// time, ignoring those other tests left in the outbox.
+		claim := func(at time.Time) []string {
+			events, err := s.ClaimEvents(ctx, at, time.Minute, 1000)
+			require.NoError(t, err)
+			var ids []string
+			for i, e := range events {
+				if i > 0 {
+					require.Less(t, events[i-1].Seq, e.Seq)
+				}
+				if e.AggregateId == a || e.AggregateId == b {
+					ids = append(ids, e.Id)
+				}
+			}
+			return ids
+		}
+
+		a1, a2, b1 := add(a), add(a), add(b)
+		require.Equal(t, []string{a1, a2, b1}, claim(now))
+		require.Empty(t, claim(now), "claimed events are leased")
+
+		require.NoError(t, s.MarkEventPublished(ctx, b1, now))
+		require.NoError(t, s.MarkEventFailed(ctx, a1, now.Add(2*time.Minute), "unavailable"))
+		b2 := add(b)
+		require.Equal(t, []string{b2}, claim(now.Add(90*time.Second)), "a waits for the retry of its first event")
+		require.NoError(t, s.DeferEvent(ctx, b2, now.Add(100*time.Second)))
+		require.Equal(t, []string{b2}, claim(now.Add(100*time.Second)), "deferred events end their lease early")
+
+		events, err := s.ClaimEvents(ctx, now.Add(3*time.Minute), time.Minute, 1000)
+		require.NoError(t, err)
+		var ids []string
+		for _, e := range events {
+			switch e.AggregateId {
+			case a, b:
+				ids = append(ids, e.Id)
+			}
+			if e.Id == a1 {
+				require.Equal(t, 1, e.Attempts)
+				require.JSONEq(t, `{}`, string(e.Payload))
+				require.WithinDuration(t, now.Add(-time.Minute), e.OccurredAt, time.Millisecond)
+			}
+			if e.Id == b2 {
+				require.Zero(t, e.Attempts, "deferring does not count an attempt")
+			}
+		}
+		require.Equal(t, []string{a1, a2, b2}, ids)
+
+		for _, id := range ids {
+			require.NoError(t, s.MarkEventPublished(ctx, id, now))
+		}
+		n, err := s.PurgePublishedEvents(ctx, now.Add(time.Second))
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, n, int64(4))
+		require.Empty(t, claim(now.Add(time.Hour)))
+	})
+}
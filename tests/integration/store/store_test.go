@@ -0,0 +1,182 @@
+package store_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
+	"go-web/internal/infra/store"
+	"go-web/tests/utils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// forEachStore runs test against a fresh memory and SQLite store, and against
+// Postgres when STORE_DRIVER is postgres, so that they all behave alike.
+func forEachStore(t *testing.T, test func(t *testing.T, s ports.Store)) {
+	stores := map[string]func() (ports.Store, error){
+		"memory": func() (ports.Store, error) { return store.NewMemStore(), nil },
+		"sqlite": func() (ports.Store, error) { return store.NewSQLiteStore(":memory:") },
+	}
+	if os.Getenv("STORE_DRIVER") == "postgres" {
+		stores["postgres"] = func() (ports.Store, error) {
+			return store.NewPgStore(context.Background(), utils.PostgresDSN)
+		}
+	}
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			s, err := open()
+			require.NoError(t, err)
+			test(t, s)
+		})
+	}
+}
+
+func TestUserStore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s ports.Store) {
+		ctx := context.Background()
+
+		t.Run("creates users with defaults", func(t *testing.T) {
+			user := newUser()
+			created, err := s.Create(ctx, user)
+			require.NoError(t, err)
+			require.Equal(t, user.Email, created.Email)
+			require.Equal(t, []string{"user"}, created.Roles)
+			require.False(t, created.CreatedAt.IsZero())
+			require.Equal(t, models.UserActive, created.Status())
+		})
+
+		t.Run("emails are unique ignoring case", func(t *testing.T) {
+			user := newUser()
+			_, err := s.Create(ctx, user)
+			require.NoError(t, err)
+
+			other := newUser()
+			taken := *other
+			taken.Email = strings.ToUpper(user.Email)
+			_, err = s.Create(ctx, &taken)
+			require.ErrorIs(t, err, ports.ErrUniqueViolation)
+
+			found, err := s.FindByEmail(ctx, strings.ToUpper(user.Email))
+			require.NoError(t, err)
+			require.Equal(t, user.Id, found.Id)
+
+			_, err = s.Create(ctx, other)
+			require.NoError(t, err)
+			require.ErrorIs(t, s.UpdateEmail(ctx, other.Id, strings.ToUpper(user.Email)), ports.ErrUniqueViolation)
+		})
+
+		t.Run("audit entries need their user", func(t *testing.T) {
+			err := s.AddAudit(ctx, &models.AuditEntry{Id: uuid.NewString(), UserId: "missing", Action: "test", CreatedAt: time.Now()})
+			require.ErrorIs(t, err, ports.ErrForeignKeyViolation)
+		})
+
+		t.Run("updates only unmodified users", func(t *testing.T) {
+			created, err := s.Create(ctx, newUser())
+			require.NoError(t, err)
+			created.DisplayName = "First"
+			updated, err := s.Update(ctx, created)
+			require.NoError(t, err)
+			require.Equal(t, "First", updated.DisplayName)
+
+			created.DisplayName = "Stale"
+			stale, err := s.Update(ctx, created)
+			require.NoError(t, err)
+			require.Nil(t, stale)
+
+			found, err := s.FindByID(ctx, created.Id)
+			require.NoError(t, err)
+			require.Equal(t, "First", found.DisplayName)
+		})
+
+		t.Run("purges deleted users with their audit", func(t *testing.T) {
+			created, err := s.Create(ctx, newUser())
+			require.NoError(t, err)
+			require.NoError(t, s.AddAudit(ctx, &models.AuditEntry{Id: uuid.NewString(), UserId: created.Id, Action: "test", CreatedAt: time.Now()}))
+			require.NoError(t, s.SoftDelete(ctx, created.Id, time.Now().Add(-time.Hour)))
+
+			n, err := s.PurgeDeleted(ctx, time.Now())
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, n, int64(1))
+			found, err := s.FindByID(ctx, created.Id)
+			require.NoError(t, err)
+			require.Nil(t, found)
+			entries, err := s.ListAudit(ctx, created.Id)
+			require.NoError(t, err)
+			require.Empty(t, entries)
+		})
+	})
+}
+
+func TestListUsers(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s ports.Store) {
+		ctx := context.Background()
+		prefix := "list" + uuid.NewString()[:8]
+		var ids []string
+		for _, name := range []string{"c", "a", "b"} {
+			user := newUser()
+			user.Email = prefix + name + "@test.com"
+			_, err := s.Create(ctx, user)
+			require.NoError(t, err)
+			ids = append(ids, user.Id)
+			time.Sleep(time.Millisecond)
+		}
+		require.NoError(t, s.UpdateRoles(ctx, ids[2], []string{"user", models.RoleAdmin}))
+		now := time.Now()
+		require.NoError(t, s.SetDisabled(ctx, ids[1], &now))
+
+		emails := func(q models.ListQuery) []string {
+			users, err := s.ListUsers(ctx, q)
+			require.NoError(t, err)
+			var out []string
+			for _, u := range users {
+				out = append(out, u.Email)
+			}
+			return out
+		}
+		byPrefix := models.Filter{Field: models.UserFieldEmail, Op: models.OpPrefix, Value: strings.ToUpper(prefix)}
+
+		t.Run("sorts and pages by email", func(t *testing.T) {
+			q := models.ListQuery{Filters: []models.Filter{byPrefix}, Sort: models.UserFieldEmail, Limit: 2}
+			require.Equal(t, []string{prefix + "a@test.com", prefix + "b@test.com"}, emails(q))
+			q.After = &models.Cursor{Value: prefix + "b@test.com", Id: ids[2]}
+			require.Equal(t, []string{prefix + "c@test.com"}, emails(q))
+		})
+
+		t.Run("sorts and pages by creation time", func(t *testing.T) {
+			q := models.ListQuery{Filters: []models.Filter{byPrefix}, Sort: models.UserFieldCreatedAt, Desc: true, Limit: 10}
+			require.Equal(t, []string{prefix + "b@test.com", prefix + "a@test.com", prefix + "c@test.com"}, emails(q))
+
+			last, err := s.FindByID(ctx, ids[2])
+			require.NoError(t, err)
+			q.After = &models.Cursor{Value: last.CreatedAt.Format(time.RFC3339Nano), Id: last.Id}
+			require.Equal(t, []string{prefix + "a@test.com", prefix + "c@test.com"}, emails(q))
+		})
+
+		t.Run("filters", func(t *testing.T) {
+			q := func(f models.Filter) models.ListQuery {
+				return models.ListQuery{Filters: []models.Filter{byPrefix, f}, Sort: models.UserFieldEmail, Limit: 10}
+			}
+			require.Equal(t, []string{prefix + "b@test.com"}, emails(q(models.Filter{Field: models.UserFieldRole, Op: models.OpEq, Value: models.RoleAdmin})))
+			require.Equal(t, []string{prefix + "a@test.com"}, emails(q(models.Filter{Field: models.UserFieldStatus, Op: models.OpEq, Value: models.UserDisabled})))
+			require.Len(t, emails(q(models.Filter{Field: models.UserFieldCreatedAt, Op: models.OpGte, Value: now.Add(-time.Minute)})), 3)
+			require.Empty(t, emails(q(models.Filter{Field: models.UserFieldCreatedAt, Op: models.OpLt, Value: now.Add(-time.Minute)})))
+		})
+
+		t.Run("rejects unknown fields", func(t *testing.T) {
+			_, err := s.ListUsers(ctx, models.ListQuery{Sort: "password_hash", Limit: 1})
+			require.Error(t, err)
+			_, err = s.ListUsers(ctx, models.ListQuery{
+				Filters: []models.Filter{{Field: "password_hash", Op: models.OpEq, Value: "x"}},
+				Sort:    models.UserFieldEmail,
+				Limit:   1,
+			})
+			require.Error(t, err)
+		})
+	})
+}
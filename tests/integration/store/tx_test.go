@@ -0,0 +1,144 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
+	"go-web/internal/infra/store"
+	"go-web/internal/shared"
+	"go-web/tests/utils"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func newUser() *models.User {
+	return &models.User{
+		Id:           uuid.NewString(),
+		Email:        "tx" + shared.RandString(10) + "@test.com",
+		PasswordHash: "hash",
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	forEachStore(t, testWithTx)
+}
+
+func testWithTx(t *testing.T, s ports.Store) {
+	ctx := context.Background()
+
+	t.Run("commits when fn succeeds", func(t *testing.T) {
+		user := newUser()
+		err := s.WithTx(ctx, func(tx ports.Store) error {
+			if _, err := tx.Create(ctx, user); err != nil {
+				return err
+			}
+			return tx.AddAudit(ctx, &models.AuditEntry{Id: uuid.NewString(), UserId: user.Id, Action: "test"})
+		})
+		require.NoError(t, err)
+		found, err := s.FindByID(ctx, user.Id)
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		entries, err := s.ListAudit(ctx, user.Id)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+	})
+
+	t.Run("rolls back when fn fails", func(t *testing.T) {
+		user := newUser()
+		failure := errors.New("failure")
+		err := s.WithTx(ctx, func(tx ports.Store) error {
+			if _, err := tx.Create(ctx, user); err != nil {
+				return err
+			}
+			return failure
+		})
+		require.ErrorIs(t, err, failure)
+		found, err := s.FindByID(ctx, user.Id)
+		require.NoError(t, err)
+		require.Nil(t, found)
+	})
+
+	t.Run("rolls back and repanics when fn panics", func(t *testing.T) {
+		user := newUser()
+		require.PanicsWithValue(t, "boom", func() {
+			//nolint:errcheck
+			s.WithTx(ctx, func(tx ports.Store) error {
+				if _, err := tx.Create(ctx, user); err != nil {
+					return err
+				}
+				panic("boom")
+			})
+		})
+		found, err := s.FindByID(ctx, user.Id)
+		require.NoError(t, err)
+		require.Nil(t, found)
+	})
+
+	t.Run("nested calls join the transaction", func(t *testing.T) {
+		user := newUser()
+		failure := errors.New("failure")
+		err := s.WithTx(ctx, func(tx ports.Store) error {
+			err := tx.WithTx(ctx, func(inner ports.Store) error {
+				_, err := inner.Create(ctx, user)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			return failure
+		})
+		require.ErrorIs(t, err, failure)
+		found, err := s.FindByID(ctx, user.Id)
+		require.NoError(t, err)
+		require.Nil(t, found)
+	})
+
+}
+
+// TestWithTxRetries covers the retries of Postgres, the only store whose
+// transactions fail to serialize.
+func TestWithTxRetries(t *testing.T) {
+	if os.Getenv("STORE_DRIVER") != "postgres" {
+		t.Skip("needs STORE_DRIVER=postgres")
+	}
+	ctx := context.Background()
+	s, err := store.NewPgStore(ctx, utils.PostgresDSN)
+	require.NoError(t, err)
+
+	t.Run("retries serialization failures", func(t *testing.T) {
+		user := newUser()
+		attempts := 0
+		err := s.WithTx(ctx, func(tx ports.Store) error {
+			attempts++
+			if _, err := tx.Create(ctx, user); err != nil {
+				return err
+			}
+			if attempts < 3 {
+				return &pq.Error{Code: "40001"}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, attempts)
+		found, err := s.FindByID(ctx, user.Id)
+		require.NoError(t, err)
+		require.NotNil(t, found)
+	})
+
+	t.Run("gives up after the last retry", func(t *testing.T) {
+		attempts := 0
+		err := s.WithTx(ctx, func(tx ports.Store) error {
+			attempts++
+			return &pq.Error{Code: "40001"}
+		})
+		var pqErr *pq.Error
+		require.ErrorAs(t, err, &pqErr)
+		require.Equal(t, 1+store.DefaultTxOptions.MaxRetries, attempts)
+	})
+}
@@ -23,6 +23,11 @@ func (m *MockCache) SetWithTTL(key string, value interface{}, ttl int) error {
 	return args.Error(0)
 }
 
+func (m *MockCache) Add(key string, value interface{}, ttl int) (bool, error) {
+	args := m.Called(key, value, ttl)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockCache) Delete(key string) error {
 	args := m.Called(key)
 	return args.Error(0)
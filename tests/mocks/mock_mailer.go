@@ -0,0 +1,18 @@
+package mocks
+
+import (
+	"context"
+
+	"go-web/internal/core/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) Send(ctx context.Context, mail *models.Mail) error {
+	args := m.Called(ctx, mail)
+	return args.Error(0)
+}
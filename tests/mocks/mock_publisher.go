@@ -0,0 +1,18 @@
+package mocks
+
+import (
+	"context"
+
+	"go-web/internal/core/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPublisher struct {
+	mock.Mock
+}
+
+func (m *MockPublisher) Publish(ctx context.Context, event *models.Event) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
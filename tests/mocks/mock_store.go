@@ -2,8 +2,10 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"go-web/internal/core/models"
+	"go-web/internal/core/ports"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -12,6 +14,12 @@ type MockStore struct {
 	mock.Mock
 }
 
+// WithTx runs fn on the mock itself, so the expectations of a test apply
+// inside the transaction as well.
+func (m *MockStore) WithTx(ctx context.Context, fn func(tx ports.Store) error) error {
+	return fn(m)
+}
+
 func (m *MockStore) Create(ctx context.Context, user *models.User) (*models.User, error) {
 	args := m.Called(ctx, user)
 	return args.Get(0).(*models.User), args.Error(1)
@@ -21,3 +29,98 @@ func (m *MockStore) FindByEmail(ctx context.Context, email string) (*models.User
 	args := m.Called(ctx, email)
 	return args.Get(0).(*models.User), args.Error(1)
 }
+
+func (m *MockStore) FindByID(ctx context.Context, id string) (*models.User, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockStore) Update(ctx context.Context, user *models.User) (*models.User, error) {
+	args := m.Called(ctx, user)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockStore) UpdateLastLogin(ctx context.Context, id string, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockStore) UpdatePassword(ctx context.Context, id, passwordHash string) error {
+	args := m.Called(ctx, id, passwordHash)
+	return args.Error(0)
+}
+
+func (m *MockStore) UpdateEmail(ctx context.Context, id, email string) error {
+	args := m.Called(ctx, id, email)
+	return args.Error(0)
+}
+
+func (m *MockStore) SoftDelete(ctx context.Context, id string, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockStore) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockStore) ListUsers(ctx context.Context, query models.ListQuery) ([]models.User, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *MockStore) SetDisabled(ctx context.Context, id string, at *time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockStore) ResetTwoFactor(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockStore) UpdateRoles(ctx context.Context, id string, roles []string) error {
+	args := m.Called(ctx, id, roles)
+	return args.Error(0)
+}
+
+func (m *MockStore) AddAudit(ctx context.Context, entry *models.AuditEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockStore) ListAudit(ctx context.Context, userId string) ([]models.AuditEntry, error) {
+	args := m.Called(ctx, userId)
+	return args.Get(0).([]models.AuditEntry), args.Error(1)
+}
+
+func (m *MockStore) AddEvent(ctx context.Context, event *models.Event) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockStore) ClaimEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Event, error) {
+	args := m.Called(ctx, now, lease, limit)
+	return args.Get(0).([]models.Event), args.Error(1)
+}
+
+func (m *MockStore) MarkEventPublished(ctx context.Context, id string, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockStore) MarkEventFailed(ctx context.Context, id string, retryAt time.Time, reason string) error {
+	args := m.Called(ctx, id, retryAt, reason)
+	return args.Error(0)
+}
+
+func (m *MockStore) DeferEvent(ctx context.Context, id string, availableAt time.Time) error {
+	args := m.Called(ctx, id, availableAt)
+	return args.Error(0)
+}
+
+func (m *MockStore) PurgePublishedEvents(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}